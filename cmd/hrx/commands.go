@@ -0,0 +1,428 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-corelibs/hrx"
+	"github.com/go-corelibs/hrx/lint"
+	"github.com/go-corelibs/hrx/lsp"
+)
+
+const (
+	exitOK = iota
+	exitInvalid
+	exitUsage
+	exitIO
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) (err error)
+}
+
+var commands = []*command{
+	{name: "ls", args: "<archive.hrx> [pattern...]", summary: "list all entry pathnames", run: (*cli).ls},
+	{name: "cat", args: "<archive.hrx> <pathname...>", summary: "print the body of each entry", run: (*cli).cat},
+	{name: "x", args: "<archive.hrx> [-C dir] [-prefix dir] [-strip count] [pattern...]", summary: "extract entries to a directory", run: (*cli).extract},
+	{name: "c", args: "<output.hrx> <dir> [-i pattern] [-e pattern]", summary: "create an archive from a directory", run: (*cli).create},
+	{name: "check", args: "<archive.hrx...>", summary: "report every problem with one or more archives", run: (*cli).check},
+	{name: "lint", args: "<archive.hrx...> [-fix] [-enable rule] [-disable rule]", summary: "report style problems, optionally fixing them", run: (*cli).lint},
+	{name: "diff", args: "<a.hrx> <b.hrx> [-r]", summary: "print the differences of two archives", run: (*cli).diff},
+	{name: "merge", args: "<base.hrx> <ours.hrx> <theirs.hrx>", summary: "git merge driver, merges into ours.hrx", run: (*cli).merge},
+	{name: "textconv", args: "<archive.hrx>", summary: "git diff textconv, prints a normalized archive", run: (*cli).textconv},
+	{name: "lsp", args: "", summary: "run a language server over stdin and stdout", run: (*cli).lsp},
+}
+
+// usageError indicates the command-line arguments given were not valid
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func newUsageError(format string, argv ...interface{}) *usageError {
+	return &usageError{message: fmt.Sprintf(format, argv...)}
+}
+
+// patterns is a flag.Value for repeatable pattern options
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+type cli struct {
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	verbose bool
+	current *command
+	flags   *flag.FlagSet
+}
+
+// run is the entrypoint of the hrx command, returning the process exit code
+func run(args []string, stdout, stderr io.Writer) (code int) {
+	c := &cli{stdin: os.Stdin, stdout: stdout, stderr: stderr}
+
+	global := flag.NewFlagSet("hrx", flag.ContinueOnError)
+	global.SetOutput(io.Discard)
+	global.BoolVar(&c.verbose, "v", false, "report progress to stderr")
+	if err := global.Parse(args); err != nil {
+		return c.fail(err)
+	} else if args = global.Args(); len(args) == 0 {
+		c.usage()
+		return exitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			c.current = cmd
+			return c.fail(cmd.run(c, args[1:]))
+		}
+	}
+
+	return c.fail(newUsageError("unknown command %q", args[0]))
+}
+
+// usage prints the top-level usage or the usage of the current command
+func (c *cli) usage() {
+	if c.current != nil {
+		_, _ = fmt.Fprintf(c.stderr, "usage: hrx %s\n", strings.TrimSpace(c.current.name+" "+c.current.args))
+		if c.flags != nil {
+			c.flags.SetOutput(c.stderr)
+			c.flags.PrintDefaults()
+		}
+		return
+	}
+	_, _ = fmt.Fprintf(c.stderr, "usage: hrx [-v] <command> [options] [arguments]\n\ncommands:\n")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(c.stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// fail prints diagnostics for the error given and returns the exit code, any
+// joined errors are printed individually with the most severe exit code
+// returned
+func (c *cli) fail(err error) (code int) {
+	if err == nil {
+		return exitOK
+	}
+
+	// joined errors are reported individually, with the most severe code
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, ee := range joined.Unwrap() {
+			code = max(code, c.fail(ee))
+		}
+		return
+	}
+
+	if errors.Is(err, errDifferences) || errors.Is(err, errProblems) {
+		return exitInvalid
+	} else if errors.Is(err, flag.ErrHelp) {
+		c.usage()
+		return exitOK
+	}
+
+	var ue *usageError
+	if errors.As(err, &ue) {
+		_, _ = fmt.Fprintf(c.stderr, "hrx: %v\n", err)
+		c.usage()
+		return exitUsage
+	} else if e, ok := hrx.AsError(err); ok {
+		_, _ = fmt.Fprintln(c.stderr, diagnostic(e))
+		if c.verbose {
+			// the source line, caret and hint follow the first line
+			if _, snippet, found := strings.Cut(fmt.Sprintf("%+v", e), "\n"); found {
+				_, _ = fmt.Fprintln(c.stderr, snippet)
+			}
+		}
+		return exitInvalid
+	} else if errors.Is(err, hrx.ErrNotFound) || errors.Is(err, errConflicts) {
+		_, _ = fmt.Fprintf(c.stderr, "hrx: %v\n", err)
+		return exitInvalid
+	}
+
+	_, _ = fmt.Fprintf(c.stderr, "hrx: %v\n", err)
+	return exitIO
+}
+
+// diagnostic formats an *hrx.Error in the conventional file:line: message
+// form, or file:line:column: message when the column is known
+func diagnostic(e *hrx.Error) (text string) {
+	if e.Column > 0 {
+		text = fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Base)
+	} else {
+		text = fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Base)
+	}
+	if e.Wrap != nil {
+		text += fmt.Sprintf(": %v", e.Wrap)
+	}
+	return
+}
+
+// parse parses the flags of the current command, allowing flags to be given
+// before, between or after the positional arguments. The number of positional
+// arguments must be at least minArgs and, if maxArgs is not negative, at most
+// maxArgs
+func (c *cli) parse(args []string, minArgs, maxArgs int) (positional []string, err error) {
+	if c.flags == nil {
+		c.flags = flag.NewFlagSet(c.current.name, flag.ContinueOnError)
+	}
+	c.flags.SetOutput(io.Discard)
+	c.flags.BoolVar(&c.verbose, "v", c.verbose, "report progress to stderr")
+
+	for {
+		if err = c.flags.Parse(args); err != nil {
+			if !errors.Is(err, flag.ErrHelp) {
+				err = newUsageError("%v", err)
+			}
+			return
+		} else if args = c.flags.Args(); len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) < minArgs || (maxArgs >= 0 && len(positional) > maxArgs) {
+		err = newUsageError("wrong number of arguments")
+	}
+	return
+}
+
+// reporter returns a hrx.ReporterFn when verbose, nil otherwise
+func (c *cli) reporter() hrx.ReporterFn {
+	if !c.verbose {
+		return nil
+	}
+	return func(archive, pathname, note string, argv ...interface{}) {
+		if pathname == "" {
+			_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", archive, note)
+			return
+		}
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s %s\n", archive, note, pathname)
+	}
+}
+
+// load reads and parses the archive file given, filesystem errors are
+// returned as-is and parsing errors as *hrx.Error values
+func (c *cli) load(filename string) (a hrx.Archive, err error) {
+	return c.loadWith(filename, nil)
+}
+
+// loadWith is like load with the parsing options given, lenient parsing
+// returns every problem found as a joined error
+func (c *cli) loadWith(filename string, options *hrx.ParseOptions) (a hrx.Archive, err error) {
+	var data []byte
+	if data, err = os.ReadFile(filename); err != nil {
+		return
+	}
+	var parsed hrx.Archive
+	if parsed, err = hrx.ParseDataWith(filename, data, options); err == nil {
+		a = parsed
+		a.SetReporter(c.reporter())
+		return
+	}
+	// diagnostics use the filename as given on the command-line
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, ee := range errs {
+		if e, ok := hrx.AsError(ee); ok {
+			e.File = filename
+		}
+	}
+	return
+}
+
+func (c *cli) ls(args []string) (err error) {
+	var positional []string
+	if positional, err = c.parse(args, 1, -1); err != nil {
+		return
+	}
+	var a hrx.Archive
+	if a, err = c.load(positional[0]); err != nil {
+		return
+	}
+	var fn hrx.SelectFn
+	if len(positional) > 1 {
+		fn = hrx.Glob(positional[1:]...)
+	}
+	for _, pathname := range a.Select(fn) {
+		_, _ = fmt.Fprintln(c.stdout, pathname)
+	}
+	return
+}
+
+func (c *cli) cat(args []string) (err error) {
+	var positional []string
+	if positional, err = c.parse(args, 2, -1); err != nil {
+		return
+	}
+	var a hrx.Archive
+	if a, err = c.load(positional[0]); err != nil {
+		return
+	}
+	for _, pathname := range positional[1:] {
+		if body, _, ok := a.Get(pathname); !ok {
+			return fmt.Errorf("%s: %s: %w", positional[0], pathname, hrx.ErrNotFound)
+		} else if _, err = io.WriteString(c.stdout, body); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *cli) extract(args []string) (err error) {
+	c.flags = flag.NewFlagSet(c.current.name, flag.ContinueOnError)
+	dir := c.flags.String("C", ".", "extract to `dir`")
+	prefix := c.flags.String("prefix", "", "only extract entries within `dir`, removing it from the pathnames")
+	strip := c.flags.Int("strip", 0, "remove `count` leading path components from the pathnames")
+	var positional []string
+	if positional, err = c.parse(args, 1, -1); err != nil {
+		return
+	}
+	var a hrx.Archive
+	if a, err = c.load(positional[0]); err != nil {
+		return
+	}
+	for _, pattern := range positional[1:] {
+		if len(a.Select(hrx.Glob(pattern))) == 0 {
+			return fmt.Errorf("%s: %s: %w", positional[0], pattern, hrx.ErrNotFound)
+		}
+	}
+	options := &hrx.ExtractOptions{Prefix: *prefix, Strip: *strip}
+	if len(positional) > 1 {
+		options.Select = hrx.Glob(positional[1:]...)
+	}
+	err = a.ExtractWith(*dir, options)
+	return
+}
+
+func (c *cli) create(args []string) (err error) {
+	c.flags = flag.NewFlagSet(c.current.name, flag.ContinueOnError)
+	var options hrx.AddOptions
+	c.flags.Var((*patterns)(&options.Include), "i", "only include files matching `pattern`")
+	c.flags.Var((*patterns)(&options.Exclude), "e", "exclude files matching `pattern`")
+	var positional []string
+	if positional, err = c.parse(args, 2, 2); err != nil {
+		return
+	}
+	a := hrx.New(positional[0], "")
+	a.SetReporter(c.reporter())
+	if err = a.AddFS(os.DirFS(positional[1]), ".", &options); err != nil {
+		return
+	}
+	err = a.WriteFile(positional[0])
+	return
+}
+
+func (c *cli) check(args []string) (err error) {
+	var positional []string
+	if positional, err = c.parse(args, 1, -1); err != nil {
+		return
+	}
+	var errs []error
+	for _, filename := range positional {
+		if _, ee := c.loadWith(filename, &hrx.ParseOptions{Lenient: true}); ee != nil {
+			errs = append(errs, ee)
+		} else if c.verbose {
+			_, _ = fmt.Fprintf(c.stderr, "%s: ok\n", filename)
+		}
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// errProblems is returned by the lint command when any warnings or errors are
+// reported
+var errProblems = errors.New("lint problems found")
+
+func (c *cli) lint(args []string) (err error) {
+	c.flags = flag.NewFlagSet(c.current.name, flag.ContinueOnError)
+	var enable, disable patterns
+	c.flags.Var(&enable, "enable", "enable the lint `rule`, may be repeated")
+	c.flags.Var(&disable, "disable", "disable the lint `rule`, may be repeated")
+	fix := c.flags.Bool("fix", false, "fix the problems which can be fixed, rewriting the archives")
+	var positional []string
+	if positional, err = c.parse(args, 1, -1); err != nil {
+		return
+	}
+	config := &lint.Config{}
+	if err = config.Enable(enable...); err == nil {
+		err = config.Disable(disable...)
+	}
+	if err != nil {
+		return newUsageError("%v", err)
+	}
+
+	var errs []error
+	var failed bool
+	for _, filename := range positional {
+		var a hrx.Archive
+		if a, err = c.load(filename); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if *fix {
+			var fixed []*lint.Problem
+			if fixed, err = lint.Fix(a, config); err == nil && len(fixed) > 0 {
+				err = a.WriteFile(filename)
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		for _, p := range lint.Lint(a, config) {
+			if c.verbose {
+				_, _ = fmt.Fprintf(c.stderr, "%+v\n", p)
+			} else {
+				_, _ = fmt.Fprintln(c.stderr, p)
+			}
+			failed = failed || p.Severity != lint.SeverityInfo
+		}
+	}
+	if failed {
+		errs = append(errs, errProblems)
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+func (c *cli) lsp(args []string) (err error) {
+	if _, err = c.parse(args, 0, 0); err != nil {
+		return
+	}
+	err = lsp.NewServer().Serve(c.stdin, c.stdout)
+	return
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-corelibs/hrx"
+)
+
+var (
+	// errDifferences is returned by the diff command when the archives differ
+	errDifferences = errors.New("archives differ")
+	// errConflicts is returned by the merge command when there are conflicts
+	errConflicts = errors.New("merge conflicts")
+)
+
+// diff prints the unified difference of two archives, exiting with one when
+// there are differences, like diff(1)
+func (c *cli) diff(args []string) (err error) {
+	c.flags = flag.NewFlagSet(c.current.name, flag.ContinueOnError)
+	recurse := c.flags.Bool("r", false, "recurse into nested archives")
+	var positional []string
+	if positional, err = c.parse(args, 2, 2); err != nil {
+		return
+	}
+	var a, b hrx.Archive
+	if a, err = c.load(positional[0]); err != nil {
+		return
+	} else if b, err = c.load(positional[1]); err != nil {
+		return
+	}
+	var d *hrx.ArchiveDiff
+	if d, err = hrx.Diff(a, b, &hrx.DiffOptions{Recurse: *recurse}); err != nil {
+		return
+	} else if d.Empty() {
+		return
+	}
+	if _, err = io.WriteString(c.stdout, d.Unified()); err == nil {
+		err = errDifferences
+	}
+	return
+}
+
+// merge is a git merge driver, configured with:
+//
+//	git config merge.hrx.driver "hrx merge %O %A %B"
+//
+// The merged archive is written to the ours archive, which git uses as the
+// result. Conflicts are reported to stderr and exit with one, leaving the
+// conflict markers within the merged entry bodies
+func (c *cli) merge(args []string) (err error) {
+	c.flags = flag.NewFlagSet(c.current.name, flag.ContinueOnError)
+	var options hrx.MergeOptions
+	c.flags.StringVar(&options.OursLabel, "ours", "ours", "conflict marker `label` of ours")
+	c.flags.StringVar(&options.TheirsLabel, "theirs", "theirs", "conflict marker `label` of theirs")
+	var positional []string
+	if positional, err = c.parse(args, 3, 3); err != nil {
+		return
+	}
+
+	var base, ours, theirs hrx.Archive
+	if base, err = c.loadBase(positional[0]); err != nil {
+		return
+	} else if ours, err = c.load(positional[1]); err != nil {
+		return
+	} else if theirs, err = c.load(positional[2]); err != nil {
+		return
+	}
+
+	var merged hrx.Archive
+	var conflicts []*hrx.MergeConflict
+	if merged, conflicts, err = hrx.Merge(base, ours, theirs, &options); err != nil {
+		return
+	} else if err = merged.WriteFile(positional[1]); err != nil {
+		return
+	}
+
+	for _, conflict := range conflicts {
+		pathname := conflict.Pathname
+		if pathname == "" {
+			pathname = "(archive comment)"
+		}
+		_, _ = fmt.Fprintf(c.stderr, "%s:%d: %s conflict: %s\n", positional[1], conflict.Line, conflict.Kind, pathname)
+	}
+	if len(conflicts) > 0 {
+		err = fmt.Errorf("%d %w", len(conflicts), errConflicts)
+	}
+	return
+}
+
+// loadBase is like load except that an empty file, which git uses as the
+// base of add/add conflicts, is an empty archive
+func (c *cli) loadBase(filename string) (a hrx.Archive, err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(filename); err != nil {
+		return
+	} else if info.Size() == 0 {
+		a = hrx.New(filename, "")
+		return
+	}
+	return c.load(filename)
+}
+
+// textconv prints the archive with a normalized boundary for git diff,
+// configured with:
+//
+//	git config diff.hrx.textconv "hrx textconv"
+//
+// Archives which fail to parse or normalize are printed as-is
+func (c *cli) textconv(args []string) (err error) {
+	var positional []string
+	if positional, err = c.parse(args, 1, 1); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = os.ReadFile(positional[0]); err != nil {
+		return
+	}
+	output := string(data)
+	if a, ee := hrx.ParseData(positional[0], data); ee == nil {
+		if ee = a.Normalize(); ee == nil {
+			output = a.String()
+		}
+	}
+	_, err = io.WriteString(c.stdout, output)
+	return
+}
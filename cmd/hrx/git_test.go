@@ -0,0 +1,148 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestMain allows git to run this test binary as the hrx command
+func TestMain(m *testing.M) {
+	if os.Getenv("HRX_TEST_COMMAND") == "1" {
+		os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+func TestGit(t *testing.T) {
+	gitBin, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not found")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("git drivers", t, func() {
+		repo := t.TempDir()
+		git := func(args ...string) (output string, err error) {
+			cmd := exec.Command(gitBin, args...)
+			cmd.Dir = repo
+			cmd.Env = append(os.Environ(),
+				"HRX_TEST_COMMAND=1",
+				"GIT_CONFIG_NOSYSTEM=1",
+				"HOME="+repo,
+				"GIT_AUTHOR_NAME=testing", "GIT_AUTHOR_EMAIL=testing@example.com",
+				"GIT_COMMITTER_NAME=testing", "GIT_COMMITTER_EMAIL=testing@example.com",
+			)
+			data, err := cmd.CombinedOutput()
+			return string(data), err
+		}
+		write := func(contents string) {
+			So(os.WriteFile(filepath.Join(repo, "fixture.hrx"), []byte(contents), 0640), ShouldBeNil)
+		}
+		commit := func(message string) {
+			_, ee := git("add", "-A")
+			So(ee, ShouldBeNil)
+			_, ee = git("commit", "-q", "-m", message)
+			So(ee, ShouldBeNil)
+		}
+
+		for _, args := range [][]string{
+			{"init", "-q", "-b", "main"},
+			{"config", "merge.hrx.driver", self + " merge %O %A %B"},
+			{"config", "diff.hrx.textconv", self + " textconv"},
+		} {
+			_, ee := git(args...)
+			So(ee, ShouldBeNil)
+		}
+		So(os.WriteFile(filepath.Join(repo, ".gitattributes"), []byte("*.hrx merge=hrx diff=hrx\n"), 0640), ShouldBeNil)
+		write("<===> one.txt\none\n<===> two.txt\ntwo\n")
+		commit("base")
+
+		Convey("textconv ignores boundary changes", func() {
+			write("<=====> one.txt\none\n<=====> two.txt\ntwo\n")
+			output, ee := git("diff")
+			So(ee, ShouldBeNil)
+			So(output, ShouldEqual, "")
+			write("<=====> one.txt\none\n<=====> two.txt\nTWO\n")
+			output, ee = git("diff")
+			So(ee, ShouldBeNil)
+			So(output, ShouldContainSubstring, "-two\n+TWO\n")
+		})
+
+		Convey("merge driver", func() {
+			_, ee := git("checkout", "-q", "-b", "other")
+			So(ee, ShouldBeNil)
+			write("<===> two.txt\ntwo\n<===> one.txt\none\n<===> three.txt\nthree\n")
+			commit("reorder and add")
+			_, ee = git("checkout", "-q", "main")
+			So(ee, ShouldBeNil)
+			write("<=====> one.txt\nONE\n<=====> two.txt\ntwo\n")
+			commit("change")
+
+			output, ee := git("merge", "-q", "--no-edit", "other")
+			So(ee, ShouldBeNil)
+			data, ee := os.ReadFile(filepath.Join(repo, "fixture.hrx"))
+			So(ee, ShouldBeNil)
+			// three.txt follows one.txt as it does in other, two.txt is no longer
+			// the last entry in other so its trailing newline was removed
+			So(string(data), ShouldEqual, "<=====> one.txt\nONE\n<=====> three.txt\nthree\n\n<=====> two.txt\ntwo")
+
+			_, ee = git("checkout", "-q", "-b", "conflicting")
+			So(ee, ShouldBeNil)
+			write("<=====> one.txt\nbranch\n<=====> two.txt\ntwo\n<=====> three.txt\nthree\n")
+			commit("conflicting")
+			_, ee = git("checkout", "-q", "main")
+			So(ee, ShouldBeNil)
+			write("<=====> one.txt\nmain\n<=====> two.txt\ntwo\n<=====> three.txt\nthree\n")
+			commit("main")
+
+			output, ee = git("merge", "-q", "--no-edit", "conflicting")
+			So(ee, ShouldNotBeNil)
+			So(output, ShouldContainSubstring, "content conflict: one.txt")
+			data, ee = os.ReadFile(filepath.Join(repo, "fixture.hrx"))
+			So(ee, ShouldBeNil)
+			So(strings.HasPrefix(string(data), "<=====> one.txt\n<<<<<<< ours\nmain\n=======\nbranch\n>>>>>>> theirs\n"), ShouldBeTrue)
+		})
+
+		Convey("merge driver with add/add", func() {
+			added := filepath.Join(repo, "added.hrx")
+			_, ee := git("checkout", "-q", "-b", "other")
+			So(ee, ShouldBeNil)
+			So(os.WriteFile(added, []byte("<===> theirs.txt\ntheirs\n"), 0640), ShouldBeNil)
+			commit("add theirs")
+			_, ee = git("checkout", "-q", "main")
+			So(ee, ShouldBeNil)
+			So(os.WriteFile(added, []byte("<===> ours.txt\nours\n"), 0640), ShouldBeNil)
+			commit("add ours")
+
+			output, ee := git("merge", "-q", "--no-edit", "other")
+			So(ee, ShouldBeNil)
+			So(output, ShouldNotContainSubstring, "CONFLICT")
+			data, ee := os.ReadFile(added)
+			So(ee, ShouldBeNil)
+			So(string(data), ShouldEqual, "<===> theirs.txt\ntheirs\n\n<===> ours.txt\nours\n")
+		})
+
+	})
+}
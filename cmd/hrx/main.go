@@ -0,0 +1,64 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command hrx lists, extracts, creates and validates human-readable archives
+//
+// Usage:
+//
+//	hrx [-v] <command> [options] [arguments]
+//
+// Commands:
+//
+//	ls <archive.hrx> [pattern...]          list entry pathnames
+//	cat <archive.hrx> <pathname...>        print the body of each entry
+//	x <archive.hrx> [-C dir] [pattern...]  extract entries to a directory
+//	c <output.hrx> <dir>                   create an archive from a directory
+//	check <archive.hrx...>                 report every problem with one or more archives
+//	lint <archive.hrx...> [-fix]           report style problems, optionally fixing them
+//	diff <a.hrx> <b.hrx> [-r]              print the differences of two archives
+//	merge <base> <ours> <theirs>           git merge driver, merges into ours
+//	textconv <archive.hrx>                 git diff textconv
+//	lsp                                    run a language server over stdin and stdout
+//
+// Patterns are globs where "**" matches any number of directories and a
+// trailing slash matches a directory and everything within it. The x command
+// also accepts "-prefix dir" and "-strip count" to extract a subtree without
+// its leading directories
+//
+// The lint command accepts "-enable rule" and "-disable rule" to configure the
+// rules checked, see the lint package for the list of rules. Problems with an
+// info severity are reported without failing
+//
+// To use hrx with git, add "*.hrx merge=hrx diff=hrx" to .gitattributes and
+// configure the drivers:
+//
+//	git config merge.hrx.driver "hrx merge %O %A %B"
+//	git config diff.hrx.textconv "hrx textconv"
+//
+// Exit codes:
+//
+//	0  success
+//	1  an archive is invalid, an entry was not found, the archives differ,
+//	   there are merge conflicts or lint problems
+//	2  command-line usage error
+//	3  filesystem or other IO error
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
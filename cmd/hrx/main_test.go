@@ -0,0 +1,160 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tArchive = `<===> file.txt
+file contents
+<===> dir/nested.txt
+nested contents
+`
+
+func tRun(args ...string) (code int, stdout, stderr string) {
+	var outBuf, errBuf strings.Builder
+	code = run(args, &outBuf, &errBuf)
+	return code, outBuf.String(), errBuf.String()
+}
+
+func TestCommands(t *testing.T) {
+	Convey("hrx command", t, func() {
+		tmp := t.TempDir()
+		archive := filepath.Join(tmp, "testing.hrx")
+		So(os.WriteFile(archive, []byte(tArchive), 0640), ShouldBeNil)
+		invalid := filepath.Join(tmp, "invalid.hrx")
+		So(os.WriteFile(invalid, []byte("<===> one\n<===> one\n<===> two:\n"), 0640), ShouldBeNil)
+
+		Convey("usage", func() {
+			code, _, stderr := tRun()
+			So(code, ShouldEqual, exitUsage)
+			So(stderr, ShouldContainSubstring, "usage: hrx")
+			code, _, stderr = tRun("nope")
+			So(code, ShouldEqual, exitUsage)
+			So(stderr, ShouldContainSubstring, `unknown command "nope"`)
+			code, _, _ = tRun("ls")
+			So(code, ShouldEqual, exitUsage)
+			code, _, _ = tRun("ls", "-bad", archive)
+			So(code, ShouldEqual, exitUsage)
+		})
+
+		Convey("ls and cat", func() {
+			code, stdout, _ := tRun("ls", archive)
+			So(code, ShouldEqual, exitOK)
+			So(stdout, ShouldEqual, "file.txt\ndir/nested.txt\n")
+			code, stdout, _ = tRun("cat", archive, "dir/nested.txt", "file.txt")
+			So(code, ShouldEqual, exitOK)
+			So(stdout, ShouldEqual, "nested contents\nfile contents")
+			code, _, stderr := tRun("cat", archive, "missing.txt")
+			So(code, ShouldEqual, exitInvalid)
+			So(stderr, ShouldContainSubstring, "pathname not found")
+		})
+
+		Convey("x and c", func() {
+			dst := filepath.Join(tmp, "extracted")
+			code, _, stderr := tRun("-v", "x", archive, "-C", dst)
+			So(code, ShouldEqual, exitOK)
+			So(stderr, ShouldContainSubstring, "testing.hrx: extracted dir/nested.txt")
+			data, err := os.ReadFile(filepath.Join(dst, "dir", "nested.txt"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "nested contents\n")
+
+			created := filepath.Join(tmp, "created.hrx")
+			code, _, _ = tRun("c", created, dst, "-e", "file.txt")
+			So(code, ShouldEqual, exitOK)
+			code, stdout, _ := tRun("ls", created)
+			So(code, ShouldEqual, exitOK)
+			So(stdout, ShouldEqual, "dir/nested.txt\n")
+
+			subtree := filepath.Join(tmp, "subtree")
+			code, _, _ = tRun("x", archive, "-C", subtree, "-prefix", "dir")
+			So(code, ShouldEqual, exitOK)
+			data, err = os.ReadFile(filepath.Join(subtree, "nested.txt"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "nested contents\n")
+
+			code, _, _ = tRun("x", archive, "-C", dst, "missing.txt")
+			So(code, ShouldEqual, exitInvalid)
+			code, _, _ = tRun("c", created, filepath.Join(tmp, "missing"))
+			So(code, ShouldEqual, exitIO)
+		})
+
+		Convey("check", func() {
+			code, _, stderr := tRun("check", "-v", archive)
+			So(code, ShouldEqual, exitOK)
+			So(stderr, ShouldContainSubstring, "testing.hrx: ok")
+			code, _, stderr = tRun("check", archive, invalid)
+			So(code, ShouldEqual, exitInvalid)
+			So(stderr, ShouldStartWith, invalid+":2:7: duplicate path\n"+invalid+":3:10: malformed input: pathname contains a colon\n")
+			code, _, stderr = tRun("-v", "check", invalid)
+			So(code, ShouldEqual, exitInvalid)
+			So(stderr, ShouldContainSubstring, "\n  |       ^\n  = hint: ")
+			code, _, stderr = tRun("check", invalid, filepath.Join(tmp, "missing.hrx"))
+			So(code, ShouldEqual, exitIO)
+			So(stderr, ShouldContainSubstring, "no such file or directory")
+		})
+
+		Convey("lint", func() {
+			untidy := filepath.Join(tmp, "untidy.hrx")
+			So(os.WriteFile(untidy, []byte("<===> b.txt\nb \n<===> a.txt\na\n"), 0640), ShouldBeNil)
+			code, _, stderr := tRun("lint", archive)
+			So(code, ShouldEqual, exitOK)
+			So(stderr, ShouldEqual, archive+":2:14: info: missing final newline (final-newline)\n")
+			code, _, stderr = tRun("lint", untidy, "-enable", "unsorted")
+			So(code, ShouldEqual, exitInvalid)
+			So(stderr, ShouldContainSubstring, untidy+":2:2: warning: trailing whitespace (trailing-whitespace)\n")
+			So(stderr, ShouldContainSubstring, untidy+":3:7: warning: \"a.txt\" sorts before \"b.txt\" (unsorted)\n")
+			code, _, _ = tRun("lint", untidy, "-enable", "nope")
+			So(code, ShouldEqual, exitUsage)
+			code, _, stderr = tRun("lint", "-fix", "-enable", "unsorted", "-disable", "final-newline", untidy)
+			So(code, ShouldEqual, exitOK)
+			So(stderr, ShouldBeEmpty)
+			data, err := os.ReadFile(untidy)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "<===> a.txt\na\n\n<===> b.txt\nb")
+			code, _, _ = tRun("lint", invalid)
+			So(code, ShouldEqual, exitInvalid)
+			So(os.WriteFile(untidy, []byte("<===> a.txt\na \n"), 0640), ShouldBeNil)
+			code, _, stderr = tRun("lint", untidy, filepath.Join(tmp, "missing.hrx"))
+			So(code, ShouldEqual, exitIO)
+			So(stderr, ShouldContainSubstring, untidy+":2:2: warning: trailing whitespace (trailing-whitespace)\n")
+			So(stderr, ShouldContainSubstring, "missing.hrx: no such file or directory")
+		})
+
+		Convey("lsp", func() {
+			serve := func(input string) (code int, stderr string) {
+				var outBuf, errBuf strings.Builder
+				c := &cli{stdin: strings.NewReader(input), stdout: &outBuf, stderr: &errBuf, current: &command{name: "lsp"}}
+				return c.fail(c.lsp(nil)), errBuf.String()
+			}
+			code, _ := serve("")
+			So(code, ShouldEqual, exitOK)
+			exit := `{"jsonrpc":"2.0","method":"exit"}`
+			code, stderr := serve(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(exit), exit))
+			So(code, ShouldEqual, exitIO)
+			So(stderr, ShouldContainSubstring, "exit without shutdown")
+			code, _, _ = tRun("lsp", "extra")
+			So(code, ShouldEqual, exitUsage)
+		})
+
+	})
+}
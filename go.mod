@@ -6,7 +6,6 @@ require (
 	github.com/codeclysm/extract v2.2.0+incompatible
 	github.com/go-corelibs/path v1.4.1
 	github.com/go-corelibs/scanners v1.1.0
-	github.com/go-corelibs/slices v1.6.1
 	github.com/go-corelibs/tdata v1.3.0
 	github.com/smartystreets/goconvey v1.8.1
 )
@@ -18,7 +17,6 @@ require (
 	github.com/go-corelibs/maths v1.0.1 // indirect
 	github.com/gopherjs/gopherjs v1.17.2 // indirect
 	github.com/h2non/filetype v1.1.3 // indirect
-	github.com/iancoleman/strcase v0.3.0 // indirect
 	github.com/jtolds/gls v4.20.0+incompatible // indirect
 	github.com/juju/errors v1.0.0 // indirect
 	github.com/maruel/natural v1.1.1 // indirect
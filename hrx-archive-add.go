@@ -0,0 +1,210 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// AddOptions configures the behaviour of FromDir and Archive.AddFS
+type AddOptions struct {
+	// Include is a list of glob patterns, when not empty only files matching
+	// at least one pattern are added. Patterns are matched against both the
+	// pathname relative to the root and the base name of the file, see
+	// MatchGlob for the pattern syntax
+	Include []string
+	// Exclude is a list of glob patterns, files and directories matching
+	// any of these patterns are skipped. Patterns are matched the same as
+	// Include
+	Exclude []string
+}
+
+func (o *AddOptions) included(pathname string) (ok bool) {
+	if o == nil || len(o.Include) == 0 {
+		return true
+	}
+	return matchAny(o.Include, pathname)
+}
+
+func (o *AddOptions) excluded(pathname string) (ok bool) {
+	if o == nil {
+		return false
+	}
+	return matchAny(o.Exclude, pathname)
+}
+
+func matchAny(patterns []string, pathname string) (matched bool) {
+	return matchAnyGlob(patterns, pathname) || matchAnyGlob(patterns, path.Base(pathname))
+}
+
+// FromDir creates a new Archive from the contents of the directory given,
+// see Archive.AddFS for details
+func FromDir(dir string, options *AddOptions) (a Archive, err error) {
+	hrx := New(dir, "")
+	if err = hrx.AddFS(os.DirFS(dir), ".", options); err == nil {
+		a = hrx
+	}
+	return
+}
+
+func (a *archive) AddFS(fsys fs.FS, root string, options *AddOptions) (err error) {
+	var items []*addedItem
+
+	err = fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if name == root {
+			return nil
+		}
+
+		pathname := name
+		if root != "." {
+			pathname = strings.TrimPrefix(name, root+"/")
+		}
+
+		matched := pathname
+		if d.IsDir() {
+			matched += "/"
+		}
+		if options.excluded(matched) {
+			a.report(pathname, OpSkipped, name)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if ee := checkPathname(pathname); ee != nil {
+				return newError(name, 0, ee, ErrBadFileEntry)
+			}
+			items = append(items, &addedItem{
+				pathname: pathname + "/",
+				dir:      true,
+				used:     options.included(pathname),
+			})
+			return nil
+		} else if !d.Type().IsRegular() || !options.included(pathname) {
+			a.report(pathname, OpSkipped, name)
+			return nil
+		} else if ee := checkPathname(pathname); ee != nil {
+			return newError(name, 0, ee, ErrBadFileEntry)
+		}
+
+		data, ee := fs.ReadFile(fsys, name)
+		if ee != nil {
+			return ee
+		} else if ee = checkTextContent(name, data); ee != nil {
+			return ee
+		}
+
+		items = append(items, &addedItem{pathname: pathname, body: string(data), used: true})
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	err = a.addItems(items)
+	return
+}
+
+type addedItem struct {
+	pathname string
+	body     string
+	comment  string
+	dir      bool
+	used     bool
+	implied  bool
+}
+
+// addItems sets all used items in order, skipping any directories implied by
+// the other items unless the directory has a comment. The mutex is held for
+// the entire batch
+func (a *archive) addItems(items []*addedItem) (err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	dirs := make(map[string]*addedItem)
+	for _, item := range items {
+		if item.dir {
+			dirs[item.pathname] = item
+		}
+	}
+
+	// walking backwards so that children are processed before their parents
+	for idx := len(items) - 1; idx >= 0; idx-- {
+		if item := items[idx]; item.used {
+			for parent := path.Dir(strings.TrimSuffix(item.pathname, "/")); parent != "."; parent = path.Dir(parent) {
+				if pd, ok := dirs[parent+"/"]; ok {
+					pd.implied = true
+				}
+			}
+		}
+	}
+
+	if len(a.entries) == 0 {
+		// an empty archive can choose a boundary up-front so that all bodies,
+		// including those of nested archives, are stored verbatim
+		for a.addedCollision(items) {
+			a.boundary += 1
+		}
+	}
+
+	for _, item := range items {
+		if !item.used || (item.implied && item.comment == "") {
+			continue
+		}
+		if err = a.set(item.pathname, item.body, item.comment); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// addedCollision returns true if any of the items, or the archive comment,
+// contain a line matching the current boundary
+func (a *archive) addedCollision(items []*addedItem) (found bool) {
+	if a.comment != nil && hasBoundaryLine(*a.comment, a.boundary) {
+		return true
+	}
+	for _, item := range items {
+		if hasBoundaryLine(item.body, a.boundary) || hasBoundaryLine(item.comment, a.boundary) {
+			return true
+		}
+	}
+	return
+}
+
+// checkTextContent returns an *Error when the data given is not plain utf-8
+// text, with the line number of the first offending byte
+func checkTextContent(name string, data []byte) (err error) {
+	if idx := bytes.IndexByte(data, 0); idx >= 0 {
+		return newError(name, bytes.Count(data[:idx], []byte("\n"))+1, ErrBinaryContent, ErrMalformedInput)
+	}
+	for idx := 0; idx < len(data); {
+		r, size := utf8.DecodeRune(data[idx:])
+		if r == utf8.RuneError && size <= 1 {
+			return newError(name, bytes.Count(data[:idx], []byte("\n"))+1, ErrInvalidUnicode, ErrMalformedInput)
+		}
+		idx += size
+	}
+	return
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/tdata"
+)
+
+func TestAdd(t *testing.T) {
+	Convey("FromDir and AddFS", t, func() {
+
+		Convey("FromDir", func() {
+			tempdir, err := tdata.NewTempData("", "hrx-lib.FromDir.*")
+			So(err, ShouldBeNil)
+			defer tempdir.Destroy()
+			So(os.MkdirAll(tempdir.Join("empty", "nested"), 0770), ShouldBeNil)
+			So(os.MkdirAll(tempdir.Join("dir", "sub"), 0770), ShouldBeNil)
+			So(os.MkdirAll(tempdir.Join("skipped"), 0770), ShouldBeNil)
+			So(os.WriteFile(tempdir.Join("b.txt"), []byte("b contents\n"), 0640), ShouldBeNil)
+			So(os.WriteFile(tempdir.Join("a.txt"), []byte("a contents\n"), 0640), ShouldBeNil)
+			So(os.WriteFile(tempdir.Join("dir", "sub", "c.txt"), []byte("c contents"), 0640), ShouldBeNil)
+			So(os.WriteFile(tempdir.Join("dir", "ignore.bak"), []byte("ignored"), 0640), ShouldBeNil)
+			So(os.WriteFile(tempdir.Join("skipped", "file.txt"), []byte("skipped"), 0640), ShouldBeNil)
+
+			a, err := FromDir(tempdir.Path(), &AddOptions{Exclude: []string{"*.bak", "skipped"}})
+			So(err, ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"a.txt", "b.txt", "dir/sub/c.txt", "empty/nested/"})
+			body, _, ok := a.Get("dir/sub/c.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "c contents")
+
+			a, err = FromDir(tempdir.Path(), &AddOptions{Include: []string{"b.txt", "dir/*/*.txt"}})
+			So(err, ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"b.txt", "dir/sub/c.txt"})
+
+			So(os.WriteFile(tempdir.Join("binary.dat"), []byte("text\n\x00binary"), 0640), ShouldBeNil)
+			_, err = FromDir(tempdir.Path(), nil)
+			So(errors.Is(err, ErrBinaryContent), ShouldBeTrue)
+			e, ok := AsError(err)
+			So(ok, ShouldBeTrue)
+			So(e.Line, ShouldEqual, 2)
+		})
+
+		Convey("AddFS", func() {
+			fsys := fstest.MapFS{
+				"root/one.txt":       {Data: []byte("one")},
+				"root/two/three.txt": {Data: []byte("three")},
+				"root/bad\\name":     {Data: []byte("bad")},
+				"root/invalid.txt":   {Data: []byte{0xff, 0xfe}},
+			}
+			a := New("testing.hrx", "")
+			err := a.AddFS(fsys, "root", &AddOptions{Include: []string{"*.txt"}, Exclude: []string{"invalid.txt"}})
+			So(err, ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"one.txt", "two/three.txt"})
+			err = a.AddFS(fsys, "root", &AddOptions{Include: []string{"invalid.txt"}})
+			So(errors.Is(err, ErrInvalidUnicode), ShouldBeTrue)
+			err = a.AddFS(fsys, "root", nil)
+			So(errors.Is(err, ErrEscapeCharacter), ShouldBeTrue)
+		})
+
+		Convey("AddFS with concurrent readers", func() {
+			fsys := fstest.MapFS{
+				"one.txt": {Data: []byte("<=====> not a header\n")},
+				"two.txt": {Data: []byte("two")},
+			}
+			a := New("testing.hrx", "")
+			var wg sync.WaitGroup
+			started, done := make(chan struct{}), make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				close(started)
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						_, _ = a.GetBoundary(), a.String()
+					}
+				}
+			}()
+			<-started
+			err := a.AddFS(fsys, ".", nil)
+			close(done)
+			wg.Wait()
+			So(err, ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"one.txt", "two.txt"})
+			b, err := ParseData("testing.hrx", a.String())
+			So(err, ShouldBeNil)
+			So(b.List(), ShouldEqual, a.List())
+		})
+
+	})
+}
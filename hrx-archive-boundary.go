@@ -0,0 +1,100 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+)
+
+func (a *archive) MinimalBoundary() (size int) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.minimalBoundary(1)
+}
+
+func (a *archive) Normalize() (err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	err = a.setBoundary(a.minimalBoundary(1))
+	return
+}
+
+// raiseBoundary increases the boundary of this archive when any of the given
+// texts contain lines which would be mistaken for entry headers, returning
+// any error from setBoundary
+func (a *archive) raiseBoundary(texts ...string) (err error) {
+	for _, text := range texts {
+		if hasBoundaryLine(text, a.boundary) {
+			err = a.setBoundary(a.minimalBoundary(a.boundary + 1))
+			return
+		}
+	}
+	return
+}
+
+// minimalBoundary returns the smallest safe boundary size which is not less
+// than the minimum given
+//
+// SetBoundary gives each level of nested archives a boundary one greater than
+// the enclosing archive, so a boundary-like line found within a nested archive
+// of a given depth must not match any of the boundaries from the top-level
+// down to its own depth
+func (a *archive) minimalBoundary(minimum int) (size int) {
+	depths := make(map[int]int)
+	a.boundaryDepths(0, depths)
+	for size = minimum; ; size++ {
+		safe := true
+		for found, depth := range depths {
+			if found >= size && found <= size+depth {
+				safe = false
+				break
+			}
+		}
+		if safe {
+			return
+		}
+	}
+}
+
+// boundaryDepths records the boundary size of all boundary-like lines within
+// comments and bodies, mapped to the deepest nested archive level they were
+// found within. Nested archive structure is not recorded as SetBoundary will
+// rewrite those boundaries
+func (a *archive) boundaryDepths(depth int, depths map[int]int) {
+	record := func(text string) {
+		for _, line := range strings.Split(text, "\n") {
+			if size, ok := lineBoundary(line); ok {
+				if existing, present := depths[size]; !present || existing < depth {
+					depths[size] = depth
+				}
+			}
+		}
+	}
+
+	for _, item := range a.entries {
+		record(item.GetComment())
+		if item.IsHRX() && item.GetBody() != "" {
+			if ia, err := item.parseNested(); err == nil {
+				ia.boundaryDepths(depth+1, depths)
+				continue
+			}
+		}
+		record(item.GetBody())
+	}
+
+	if a.comment != nil {
+		record(*a.comment)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBoundary(t *testing.T) {
+	Convey("Boundary Collisions", t, func() {
+
+		Convey("Set raises the boundary", func() {
+			a := New("testing.hrx", "")
+			So(a.Set("plain.txt", "plain contents", ""), ShouldBeNil)
+			So(a.GetBoundary(), ShouldEqual, 5)
+			So(a.Set("fixture.txt", "<=====> looks like a header\n<======>\n", ""), ShouldBeNil)
+			So(a.GetBoundary(), ShouldEqual, 7)
+			b, err := ParseData("testing.hrx", a.String())
+			So(err, ShouldBeNil)
+			So(b.List(), ShouldEqual, []string{"plain.txt", "fixture.txt"})
+			body, _, _ := b.Get("fixture.txt")
+			So(body, ShouldEqual, "<=====> looks like a header\n<======>\n")
+		})
+
+		Convey("comments raise the boundary", func() {
+			a := New("testing.hrx", "")
+			So(a.Set("file", "contents", "<=====> not a header"), ShouldBeNil)
+			So(a.GetBoundary(), ShouldEqual, 6)
+			So(a.SetComment("<======>"), ShouldBeNil)
+			So(a.GetBoundary(), ShouldEqual, 7)
+			b, err := ParseData("testing.hrx", a.String())
+			So(err, ShouldBeNil)
+			comment, ok := b.GetComment()
+			So(ok, ShouldBeTrue)
+			So(comment, ShouldEqual, "<======>")
+		})
+
+		Convey("nested archives", func() {
+			a := New("testing.hrx", "")
+			So(a.Set("inner.hrx", "<=====> file\ncontents", ""), ShouldBeNil)
+			So(a.GetBoundary(), ShouldEqual, 6)
+			ia, err := a.ParseHRX("inner.hrx")
+			So(err, ShouldBeNil)
+			So(ia.GetBoundary(), ShouldEqual, 7)
+			So(ia.List(), ShouldEqual, []string{"file"})
+		})
+
+		Convey("MinimalBoundary and Normalize", func() {
+			a, err := ParseData("testing.hrx", "<=====> one\n<=> one\n<===> two\n<=====> inner.hrx\n<======> deep\n<==> deep\n")
+			So(err, ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"one", "inner.hrx"})
+			// 1 and 3 are used by body lines, 2 is used by a nested body line
+			// which will be within a nested boundary of 3
+			So(a.MinimalBoundary(), ShouldEqual, 4)
+			So(a.Normalize(), ShouldBeNil)
+			So(a.GetBoundary(), ShouldEqual, 4)
+			b, ee := ParseData("testing.hrx", a.String())
+			So(ee, ShouldBeNil)
+			So(b.List(), ShouldEqual, a.List())
+			body, _, _ := b.Get("inner.hrx//deep")
+			So(body, ShouldEqual, "<==> deep\n")
+			So(New("empty.hrx", "").MinimalBoundary(), ShouldEqual, 1)
+		})
+
+		Convey("Writer returns ErrBoundaryCollision", func() {
+			var buf strings.Builder
+			w := NewWriter("testing.hrx", &buf)
+			So(w.WriteHeader(&Header{Pathname: "file"}), ShouldBeNil)
+			_, err := io.WriteString(w, "first line\n<===")
+			So(err, ShouldBeNil)
+			_, err = io.WriteString(w, "==> split header\n")
+			So(errors.Is(err, ErrBoundaryCollision), ShouldBeTrue)
+			_, err = io.WriteString(w, "==\n<=====")
+			So(err, ShouldBeNil)
+			err = w.WriteHeader(&Header{Pathname: "other", Comment: "<=====>"})
+			So(errors.Is(err, ErrBoundaryCollision), ShouldBeTrue)
+			So(w.SetComment("ok\n<=====> nope"), ShouldBeNil)
+			So(errors.Is(w.Close(), ErrBoundaryCollision), ShouldBeTrue)
+		})
+
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-corelibs/path"
 )
@@ -27,15 +28,46 @@ var (
 	DefaultFileMode os.FileMode = 0640
 )
 
+// ExtractOptions configures the ExtractWith method, remapping each selected
+// entry pathname before it is joined with the destination directory. The
+// remapping steps are applied in the order: Prefix, Strip then Rename and
+// the resulting pathname is validated in the same way as archive pathnames
+type ExtractOptions struct {
+	// Select limits extraction to the entries selected, nil selects all
+	Select SelectFn
+	// Prefix limits extraction to the entries within the directory prefix
+	// given and removes the prefix from the pathnames extracted
+	Prefix string
+	// Strip removes the number of leading path components given, entries
+	// with no path components remaining are skipped
+	Strip int
+	// Rename is given each pathname after Prefix and Strip are applied and
+	// returns the pathname to extract to, an empty string skips the entry
+	Rename func(pathname string) (renamed string)
+}
+
 func (a *archive) ExtractTo(destination string, pathnames ...string) (err error) {
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
+	options := &ExtractOptions{}
+	if len(pathnames) > 0 {
+		options.Select = Glob(pathnames...)
+	}
+	err = a.extractTo(destination, options)
+	return
+}
 
-	check := len(pathnames) > 0
-	lookup := make(map[string]struct{})
-	for _, name := range pathnames {
-		lookup[name] = struct{}{}
+func (a *archive) ExtractWith(destination string, options *ExtractOptions) (err error) {
+	if options == nil {
+		options = &ExtractOptions{}
 	}
+	err = a.extractTo(destination, options)
+	return
+}
+
+// extractTo extracts all entries selected by the options given, remapping
+// each pathname before joining it with the destination
+func (a *archive) extractTo(destination string, options *ExtractOptions) (err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
 
 	var ok bool
 	var dst string
@@ -47,15 +79,25 @@ func (a *archive) ExtractTo(destination string, pathnames ...string) (err error)
 		}
 
 		for _, item := range a.entries {
-			if _, present := lookup[item.GetPathname()]; check && !present {
+			if options.Select != nil && !options.Select(item.clone()) {
 				// skip; pathname not included
 				a.report(item.GetPathname(), OpSkipped, item)
 				continue
 			}
 
-			fullname := filepath.Join(dst, item.GetPathname())
+			var remapped string
+			if remapped, ok = options.remap(item.GetPathname()); !ok {
+				// skip; pathname not within the remapped subtree
+				a.report(item.GetPathname(), OpSkipped, item)
+				continue
+			} else if ee := checkPathname(strings.TrimSuffix(remapped, "/")); ee != nil {
+				err = newError(a.filename, item.line, ee, ErrBadFileEntry)
+				return
+			}
+
+			fullname := filepath.Join(dst, remapped)
 
-			if ok, err = a.extractFile(item, dst, fullname); !ok {
+			if ok, err = a.extractFile(item, fullname); !ok {
 				ok, err = a.extractDir(item, fullname)
 			}
 
@@ -69,6 +111,29 @@ func (a *archive) ExtractTo(destination string, pathnames ...string) (err error)
 	return
 }
 
+// remap applies the Prefix, Strip and Rename options to the pathname given,
+// returning false if the entry is to be skipped
+func (o *ExtractOptions) remap(pathname string) (remapped string, ok bool) {
+	remapped = pathname
+	if prefix := strings.Trim(o.Prefix, "/"); prefix != "" {
+		if remapped, ok = strings.CutPrefix(remapped, prefix+"/"); !ok {
+			return
+		}
+	}
+	for count := 0; count < o.Strip && remapped != ""; count++ {
+		if idx := strings.Index(remapped, "/"); idx >= 0 {
+			remapped = remapped[idx+1:]
+		} else {
+			remapped = ""
+		}
+	}
+	if remapped != "" && o.Rename != nil {
+		remapped = o.Rename(remapped)
+	}
+	ok = remapped != ""
+	return
+}
+
 func (a *archive) makeDirIfNotExist(dst string) (err error) {
 	if path.Exists(dst) {
 		if !path.IsDir(dst) {
@@ -80,10 +145,9 @@ func (a *archive) makeDirIfNotExist(dst string) (err error) {
 	return
 }
 
-func (a *archive) extractFile(item *entry, dst, fullname string) (ok bool, err error) {
+func (a *archive) extractFile(item *entry, fullname string) (ok bool, err error) {
 	if ok = item.IsFile(); ok {
-		itemPath := filepath.Dir(item.GetPathname())
-		if err = path.MkdirAll(filepath.Join(dst, itemPath)); err == nil {
+		if err = path.MkdirAll(filepath.Dir(fullname)); err == nil {
 			if err = os.WriteFile(fullname, []byte(item.GetBody()), DefaultFileMode); err == nil {
 				a.report(item.GetPathname(), OpExtracted, fullname)
 			}
@@ -18,8 +18,6 @@ import (
 	"strings"
 )
 
-// TODO: investigate required newlines separating body and next item
-
 func (a *archive) finalize() (err error) {
 	// update the archive-level boundary
 	a.boundary = a.entries[0].boundary
@@ -28,18 +26,33 @@ func (a *archive) finalize() (err error) {
 	// check for sequential comments
 	// check for directory has contents
 	// check if the last item is archive comment
-	// enforce previous newline part of next line if boundary
+	// the newline preceding each boundary was recorded during parsing, see
+	// entry.sep and archive.syntax
+	//
+	// when parsing leniently, each problem is recorded with fail and the
+	// entry is dropped, or repaired, before continuing
 
 	var keep []*entry
 	last := len(a.entries) - 1
 	for idx, item := range a.entries {
 
 		if item.err != nil {
-			return a.error(item.line, 0, item.err, ErrMalformedInput)
+			if err = a.fail(a.errorAt(item, item.src.column, item.err, ErrMalformedInput)); err != nil {
+				return
+			}
+			continue
+		} else if item.invalid != nil {
+			if err = a.fail(item.invalid); err != nil {
+				return
+			}
+			continue
 		}
 
 		if item.boundary == 0 {
-			return a.error(item.line, 0, ErrBadBoundary, ErrMalformedInput)
+			if err = a.fail(a.errorAt(item, 2, ErrBadBoundary, ErrMalformedInput)); err != nil {
+				return
+			}
+			continue
 		}
 
 		switch {
@@ -48,10 +61,15 @@ func (a *archive) finalize() (err error) {
 			if idx == last {
 				// last item is a comment, assign to archive level
 				a.comment = item.body
+				a.commentHeader = item.header
 			} else if next := a.entries[idx+1]; next.IsComment() {
-				return a.error(next.line, 0, nil, ErrSequentialComments)
+				if err = a.fail(a.errorAt(next, 1, nil, ErrSequentialComments)); err != nil {
+					return
+				}
+				// drop this comment in favour of the next one
 			} else {
 				next.comment = item.body
+				next.commentHeader = item.header
 			}
 			// skip this actual item instance because the comment was attached
 			// to something else
@@ -59,35 +77,26 @@ func (a *archive) finalize() (err error) {
 
 		case item.IsDir():
 			if item.body != nil && len(*item.body) > 1 {
-				return a.error(item.line, 0, nil, ErrDirectoryHasContents)
+				if err = a.fail(a.errorAt(item, 0, nil, ErrDirectoryHasContents)); err != nil {
+					return
+				}
+				// keep the directory, without the contents
+				item.body = nil
 			}
-
-			//case item.IsFile():
-			//	if idx < last {
-			//		if item.body != nil {
-			//			if strings.HasSuffix(*item.body, "\n") {
-			//				*item.body = strings.TrimSuffix(*item.body, "\n")
-			//			}
-			//		}
-			//	}
-
 		}
 
 		if _, present := a.lookup[item.GetPathname()]; present {
-			return a.error(item.line, 0, nil, ErrDuplicatePath)
+			if err = a.fail(a.errorAt(item, item.src.pathColumn(0), nil, ErrDuplicatePath)); err != nil {
+				return
+			}
+			continue
 		}
 
-		var build string
-		for _, name := range strings.Split(item.GetPathname(), "/") {
-			if build != "" {
-				build += "/"
-			}
-			build += name
-			if vi, ok := a.lookup[build]; ok {
-				if vi.IsFile() {
-					return a.error(item.line, 0, nil, ErrFileAsParentDir)
-				}
+		if ee := a.checkParents(item); ee != nil {
+			if err = a.fail(ee); err != nil {
+				return
 			}
+			continue
 		}
 
 		a.lookup[item.GetPathname()] = item
@@ -95,21 +104,24 @@ func (a *archive) finalize() (err error) {
 		keep = append(keep, item)
 	}
 
-	//lastKeep := len(keep) - 1
-	//if lastKeep >= 0 {
-	//	for idx, item := range keep {
-	//		if item.IsFile() {
-	//			if idx < lastKeep {
-	//				if item.body != nil {
-	//					if strings.HasSuffix(*item.body, "\n") {
-	//						*item.body = strings.TrimSuffix(*item.body, "\n")
-	//					}
-	//				}
-	//			}
-	//		}
-	//	}
-	//}
-
 	a.entries = keep
 	return
 }
+
+// checkParents returns ErrFileAsParentDir when any of the parent directories
+// of the item are files
+func (a *archive) checkParents(item *entry) (err error) {
+	var build string
+	for _, name := range strings.Split(item.GetPathname(), "/") {
+		if build != "" {
+			build += "/"
+		}
+		build += name
+		if vi, ok := a.lookup[build]; ok {
+			if vi.IsFile() {
+				return a.errorAt(item, item.src.pathColumn(0), nil, ErrFileAsParentDir)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+var (
+	_ fs.FS        = (*archive)(nil)
+	_ fs.ReadDirFS = (*archive)(nil)
+	_ fs.StatFS    = (*archive)(nil)
+	_ fs.SubFS     = (*archive)(nil)
+
+	_ fs.File        = (*fsFile)(nil)
+	_ io.Seeker      = (*fsFile)(nil)
+	_ io.ReaderAt    = (*fsFile)(nil)
+	_ fs.ReadDirFile = (*fsDir)(nil)
+	_ fs.FileInfo    = (*fsFileInfo)(nil)
+	_ fs.DirEntry    = (*fsFileInfo)(nil)
+)
+
+func (a *archive) Open(name string) (file fs.File, err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var info *fsFileInfo
+	if info, err = a.fsStat("open", name); err != nil {
+		return
+	}
+
+	if info.IsDir() {
+		file = &fsDir{info: info, list: a.fsReadDir(name)}
+		return
+	}
+
+	file = &fsFile{info: info, Reader: strings.NewReader(info.entry.GetBody())}
+	return
+}
+
+func (a *archive) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var info *fsFileInfo
+	if info, err = a.fsStat("readdir", name); err != nil {
+		return
+	} else if !info.IsDir() {
+		err = &fs.PathError{Op: "readdir", Path: name, Err: ErrNotADirectory}
+		return
+	}
+
+	entries = a.fsReadDir(name)
+	return
+}
+
+func (a *archive) Stat(name string) (info fs.FileInfo, err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	var fi *fsFileInfo
+	if fi, err = a.fsStat("stat", name); err == nil {
+		info = fi
+	}
+	return
+}
+
+func (a *archive) Sub(dir string) (sub fs.FS, err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var info *fsFileInfo
+	if info, err = a.fsStat("sub", dir); err != nil {
+		return
+	} else if !info.IsDir() {
+		err = &fs.PathError{Op: "sub", Path: dir, Err: ErrNotADirectory}
+		return
+	}
+
+	if dir == "." {
+		sub = a
+		return
+	}
+
+	prefix := dir + "/"
+	b := newArchive(a.srcPath, "")
+	b.boundary = a.boundary
+	b.lastLine = a.lastLine
+	for _, item := range a.entries {
+		if pathname := item.GetPathname(); strings.HasPrefix(pathname, prefix) {
+			if trimmed := pathname[len(prefix):]; trimmed != "" {
+				cloned := item.clone()
+				cloned.pathname = &trimmed
+				b.entries = append(b.entries, cloned)
+				b.lookup[trimmed] = cloned
+			}
+		}
+	}
+
+	sub = b
+	return
+}
+
+// fsStat resolves the fs.ValidPath name given to either a file entry, an
+// explicit directory entry or an implied parent directory
+func (a *archive) fsStat(op, name string) (info *fsFileInfo, err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+		return
+	}
+
+	if name == "." {
+		info = newFsDirInfo(".", nil)
+		return
+	}
+
+	if item, ok := a.lookup[name]; ok && item.IsFile() {
+		info = newFsFileInfo(item)
+		return
+	} else if item, ok = a.lookup[name+"/"]; ok {
+		info = newFsDirInfo(path.Base(name), item)
+		return
+	}
+
+	// look for an implied parent directory
+	prefix := name + "/"
+	for _, item := range a.entries {
+		if strings.HasPrefix(item.GetPathname(), prefix) {
+			info = newFsDirInfo(path.Base(name), nil)
+			return
+		}
+	}
+
+	err = &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	return
+}
+
+// fsReadDir returns the sorted list of immediate children of the directory
+// name given, including any implied directories
+func (a *archive) fsReadDir(name string) (list []fs.DirEntry) {
+	var prefix string
+	if name != "." {
+		prefix = name + "/"
+	}
+
+	children := make(map[string]*fsFileInfo)
+	for _, item := range a.entries {
+		pathname := item.GetPathname()
+		if pathname == "" || !strings.HasPrefix(pathname, prefix) {
+			continue
+		}
+		remainder := pathname[len(prefix):]
+		if remainder == "" {
+			continue
+		}
+		if child, _, isDir := strings.Cut(remainder, "/"); isDir {
+			if existing, present := children[child]; !present || !existing.IsDir() || existing.entry == nil {
+				if explicit, ok := a.lookup[prefix+child+"/"]; ok {
+					children[child] = newFsDirInfo(child, explicit)
+				} else {
+					children[child] = newFsDirInfo(child, nil)
+				}
+			}
+		} else if _, present := children[child]; !present {
+			children[child] = newFsFileInfo(item)
+		}
+	}
+
+	for _, info := range children {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return
+}
+
+// fsFileInfo implements both fs.FileInfo and fs.DirEntry for files and
+// directories within an Archive
+type fsFileInfo struct {
+	name   string
+	mode   fs.FileMode
+	entry  *entry
+	nested bool
+}
+
+func newFsFileInfo(item *entry) (info *fsFileInfo) {
+	return &fsFileInfo{
+		name:  path.Base(item.GetPathname()),
+		mode:  DefaultFileMode,
+		entry: item.clone(),
+	}
+}
+
+func newFsDirInfo(name string, item *entry) (info *fsFileInfo) {
+	info = &fsFileInfo{
+		name: name,
+		mode: fs.ModeDir | clPath.DefaultPathPerms,
+	}
+	if item != nil {
+		info.entry = item.clone()
+	}
+	return
+}
+
+func (i *fsFileInfo) Name() string {
+	return i.name
+}
+
+func (i *fsFileInfo) Size() (size int64) {
+	if i.entry != nil {
+		if v, ok := i.entry.Size(); ok && i.entry.IsFile() {
+			size = int64(v)
+		}
+	}
+	return
+}
+
+func (i *fsFileInfo) Mode() fs.FileMode {
+	return i.mode
+}
+
+func (i *fsFileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (i *fsFileInfo) IsDir() bool {
+	return i.mode.IsDir()
+}
+
+// Sys returns the read-only Entry associated with this file or directory,
+// implied directories return nil
+func (i *fsFileInfo) Sys() any {
+	if i.entry != nil {
+		return Entry(i.entry)
+	}
+	return nil
+}
+
+func (i *fsFileInfo) Type() fs.FileMode {
+	return i.mode.Type()
+}
+
+func (i *fsFileInfo) Info() (fs.FileInfo, error) {
+	return i, nil
+}
+
+func (i *fsFileInfo) String() string {
+	return fs.FormatFileInfo(i)
+}
+
+// fsFile is an open file within an Archive, the body contents are captured
+// at the time of opening
+type fsFile struct {
+	*strings.Reader
+	info *fsFileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *fsFile) Close() error {
+	return nil
+}
+
+// fsDir is an open directory within an Archive, the listing is captured at
+// the time of opening
+type fsDir struct {
+	info   *fsFileInfo
+	list   []fs.DirEntry
+	offset int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *fsDir) Close() error {
+	return nil
+}
+
+func (d *fsDir) ReadDir(count int) (entries []fs.DirEntry, err error) {
+	remaining := len(d.list) - d.offset
+	if count <= 0 {
+		entries = d.list[d.offset:]
+		d.offset = len(d.list)
+		return
+	} else if remaining == 0 {
+		err = io.EOF
+		return
+	} else if count > remaining {
+		count = remaining
+	}
+	entries = d.list[d.offset : d.offset+count]
+	d.offset += count
+	return
+}
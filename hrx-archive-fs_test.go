@@ -0,0 +1,105 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestArchiveFS(t *testing.T) {
+	Convey("Archive fs.FS", t, func() {
+
+		a, err := ParseData("testing.hrx", tArchiveFsHRX)
+		So(err, ShouldBeNil)
+		So(a, ShouldNotBeNil)
+
+		Convey("fstest.TestFS", func() {
+			So(fstest.TestFS(a, "file.txt", "dir/file.txt", "dir/sub/deep.txt", "empty", "one.hrx"), ShouldBeNil)
+		})
+
+		Convey("Stat", func() {
+			info, ee := a.Stat("dir/sub")
+			So(ee, ShouldBeNil)
+			So(info.IsDir(), ShouldBeTrue)
+			So(info.Name(), ShouldEqual, "sub")
+			So(info.Sys(), ShouldBeNil)
+			info, ee = a.Stat("empty")
+			So(ee, ShouldBeNil)
+			So(info.IsDir(), ShouldBeTrue)
+			So(info.Sys(), ShouldNotBeNil)
+			info, ee = a.Stat("file.txt")
+			So(ee, ShouldBeNil)
+			So(info.IsDir(), ShouldBeFalse)
+			So(info.Size(), ShouldEqual, len("hello world\n"))
+			e, ok := info.Sys().(Entry)
+			So(ok, ShouldBeTrue)
+			So(e.GetComment(), ShouldEqual, "file comment\n")
+			_, ee = a.Stat("nope")
+			So(errors.Is(ee, fs.ErrNotExist), ShouldBeTrue)
+			_, ee = a.Stat("/file.txt")
+			So(errors.Is(ee, fs.ErrInvalid), ShouldBeTrue)
+		})
+
+		Convey("ReadDir", func() {
+			list, ee := a.ReadDir(".")
+			So(ee, ShouldBeNil)
+			var names []string
+			for _, de := range list {
+				names = append(names, de.Name())
+			}
+			So(names, ShouldEqual, []string{"dir", "empty", "file.txt", "one.hrx"})
+			_, ee = a.ReadDir("file.txt")
+			So(errors.Is(ee, ErrNotADirectory), ShouldBeTrue)
+			data, ee := fs.ReadFile(a, "dir/sub/deep.txt")
+			So(ee, ShouldBeNil)
+			So(string(data), ShouldEqual, "deep contents\n")
+		})
+
+		Convey("Sub", func() {
+			sub, ee := a.Sub("dir")
+			So(ee, ShouldBeNil)
+			So(fstest.TestFS(sub, "file.txt", "sub/deep.txt"), ShouldBeNil)
+			_, ee = a.Sub("file.txt")
+			So(errors.Is(ee, ErrNotADirectory), ShouldBeTrue)
+			same, ee := a.Sub(".")
+			So(ee, ShouldBeNil)
+			So(same, ShouldEqual, a)
+		})
+
+	})
+}
+
+var (
+	tArchiveFsHRX = `<=====>
+file comment
+<=====> file.txt
+hello world
+
+<=====> dir/file.txt
+nested file
+<=====> dir/sub/deep.txt
+deep contents
+
+<=====> empty/
+<=====> one.hrx
+<======> inner.txt
+inner
+`
+)
@@ -0,0 +1,125 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+)
+
+// NestedSeparator is the separator used to address pathnames within nested
+// archives, for example: "outer.hrx//inner.hrx//file.txt". The HRX
+// specification does not allow empty path components so this separator can
+// never be confused with a valid pathname
+const NestedSeparator = "//"
+
+// NestedPath joins the given pathnames with the NestedSeparator, producing
+// a single nested address suitable for use with Archive methods that accept
+// nested pathnames
+func NestedPath(pathnames ...string) (nested string) {
+	return strings.Join(pathnames, NestedSeparator)
+}
+
+// SplitNestedPath is the inverse of NestedPath, separating a nested address
+// into the pathnames of each archive level
+func SplitNestedPath(nested string) (pathnames []string) {
+	return strings.Split(nested, NestedSeparator)
+}
+
+// splitNestedPath separates the pathname of the outermost .hrx entry from the
+// remainder of the nested address
+func splitNestedPath(pathname string) (outer, inner string, nested bool) {
+	if outer, inner, nested = strings.Cut(pathname, NestedSeparator); nested {
+		nested = strings.HasSuffix(outer, ".hrx") && inner != ""
+	}
+	return
+}
+
+// parseNested is like parseHRX except that an empty body always produces an
+// empty archive, without adopting the entry comment as the archive comment
+func (e *entry) parseNested() (a *archive, err error) {
+	if e.body == nil || *e.body == "" {
+		if !e.hrx || e.pathname == nil {
+			err = ErrNotAnArchive
+			return
+		}
+		a = newArchive(*e.pathname, "")
+		a.boundary = e.boundary + 1
+		return
+	}
+	return e.parseHRX()
+}
+
+// updateNested parses the outer entry, calls fn with the nested archive and
+// then re-serializes the nested archive back into the outer entry body. The
+// nested boundary is raised with SetBoundary when it is not greater than the
+// boundary of this archive. When create is true and the outer entry does not
+// exist, a new (empty) nested archive is appended
+func (a *archive) updateNested(outer string, create bool, fn func(ia *archive) (err error)) (err error) {
+	this, present := a.lookup[outer]
+	if !present {
+		if !create {
+			err = ErrNotFound
+			return
+		}
+		this = newEntry(a.lastLine+1, a.boundary, outer, "", nil)
+	}
+
+	var ia *archive
+	if ia, err = this.parseNested(); err != nil {
+		return
+	}
+	ia.SetReporter(func(_, pathname, note string, argv ...interface{}) {
+		a.report(NestedPath(outer, pathname), note, argv...)
+	})
+
+	if err = fn(ia); err != nil {
+		return
+	}
+
+	if ia.GetBoundary() <= a.boundary {
+		if err = ia.SetBoundary(a.boundary + 1); err != nil {
+			return
+		}
+	}
+
+	if updated := ia.String(); updated != "" {
+		this.body = &updated
+	} else {
+		this.body = nil
+	}
+	this.sep = false
+
+	if !present {
+		a.lastLine += 1
+		a.entries = append(a.entries, this)
+		a.lookup[outer] = this
+		a.report(outer, OpAppended, this.GetBody(), "")
+	} else {
+		a.report(outer, OpUpdated, this.GetBody(), this.GetComment())
+	}
+
+	err = a.raiseBoundary(this.GetBody())
+	return
+}
+
+// getNested parses the outer entry and returns the nested archive
+func (a *archive) getNested(outer string) (ia *archive, err error) {
+	if this, present := a.lookup[outer]; present {
+		ia, err = this.parseNested()
+		return
+	}
+	err = ErrNotFound
+	return
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNested(t *testing.T) {
+	Convey("Nested Addressing", t, func() {
+
+		So(NestedPath("one.hrx", "two.hrx", "file"), ShouldEqual, "one.hrx//two.hrx//file")
+		So(SplitNestedPath("one.hrx//two.hrx//file"), ShouldEqual, []string{"one.hrx", "two.hrx", "file"})
+
+		Convey("Get and Entry", func() {
+			a, err := ParseData("testing.hrx", tSetBoundaryHRX)
+			So(err, ShouldBeNil)
+			body, comment, ok := a.Get("one.hrx//two.hrx//many.hrx")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "<======>\nMany comment")
+			So(comment, ShouldEqual, "")
+			_, _, ok = a.Get("one.hrx//nope")
+			So(ok, ShouldBeFalse)
+			_, _, ok = a.Get("nope.hrx//file")
+			So(ok, ShouldBeFalse)
+			e := a.Entry(NestedPath("one.hrx", "two.hrx"))
+			So(e, ShouldNotBeNil)
+			So(e.IsHRX(), ShouldBeTrue)
+			So(a.Entry("one.hrx//nope"), ShouldBeNil)
+			ia, ee := a.ParseHRX("one.hrx//two.hrx")
+			So(ee, ShouldBeNil)
+			So(ia.GetBoundary(), ShouldEqual, 2)
+		})
+
+		Convey("Set and Delete", func() {
+			a := New("outer.hrx", "")
+			So(a.Set("file.txt", "outer file", ""), ShouldBeNil)
+			So(a.Set(NestedPath("inner.hrx", "deep.hrx", "file.txt"), "deep file", "deep comment"), ShouldBeNil)
+			So(a.String(), ShouldEqual, "<=====> file.txt\nouter file\n<=====> inner.hrx\n<======> deep.hrx\n<=======>\ndeep comment\n<=======> file.txt\ndeep file")
+
+			b, err := ParseData("outer.hrx", a.String())
+			So(err, ShouldBeNil)
+			body, comment, ok := b.Get("inner.hrx//deep.hrx//file.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "deep file")
+			So(comment, ShouldEqual, "deep comment\n")
+
+			So(b.Set("inner.hrx//other.txt", "other", ""), ShouldBeNil)
+			_, _, ok = b.Get("inner.hrx//other.txt")
+			So(ok, ShouldBeTrue)
+			_, _, ok = b.Get("inner.hrx//deep.hrx//file.txt")
+			So(ok, ShouldBeTrue)
+
+			b.Delete("inner.hrx//deep.hrx//file.txt")
+			_, _, ok = b.Get("inner.hrx//deep.hrx//file.txt")
+			So(ok, ShouldBeFalse)
+			So(b.Entry("inner.hrx//deep.hrx"), ShouldNotBeNil)
+			b.Delete("missing.hrx//file.txt")
+			So(b.Entry("missing.hrx"), ShouldBeNil)
+
+			// emptying a nested archive leaves no separator behind
+			c, err := ParseData("outer.hrx", "<===> n.hrx\n<====> x\ny\n\n<===> d/")
+			So(err, ShouldBeNil)
+			c.Delete("n.hrx//x")
+			So(c.String(), ShouldEqual, "<===> n.hrx\n<===> d/")
+		})
+
+		Convey("nested boundaries are raised", func() {
+			a, err := ParseData("testing.hrx", "<===> inner.hrx\n<=> file\ncontents\n")
+			So(err, ShouldBeNil)
+			So(a.Set("inner.hrx//other", "more", ""), ShouldBeNil)
+			ia, ee := a.ParseHRX("inner.hrx")
+			So(ee, ShouldBeNil)
+			So(ia.GetBoundary(), ShouldEqual, 4)
+			So(ia.List(), ShouldEqual, []string{"file", "other"})
+		})
+
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"slices"
+)
+
+func (a *archive) InsertBefore(pathname, newPath, body, comment string) (err error) {
+	return a.insert(pathname, newPath, body, comment, 0)
+}
+
+func (a *archive) InsertAfter(pathname, newPath, body, comment string) (err error) {
+	return a.insert(pathname, newPath, body, comment, 1)
+}
+
+// insert sets the newPath entry and moves it to the position of the pathname
+// entry plus the offset given
+func (a *archive) insert(pathname, newPath, body, comment string, offset int) (err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	outer, inner, nested := splitNestedPath(pathname)
+	newOuter, newInner, newNested := splitNestedPath(newPath)
+	if nested || newNested {
+		if !nested || !newNested || outer != newOuter {
+			err = ErrNotFound
+			return
+		}
+		err = a.updateNested(outer, false, func(ia *archive) (err error) {
+			return ia.insert(inner, newInner, body, comment, offset)
+		})
+		return
+	}
+
+	index := a.indexOf(pathname)
+	if index < 0 {
+		err = ErrNotFound
+		return
+	} else if _, present := a.lookup[newPath]; present {
+		err = a.error(a.entries[index].line, nil, ErrDuplicatePath)
+		return
+	} else if err = a.set(newPath, body, comment); err == nil {
+		a.move(len(a.entries)-1, index+offset)
+		a.renumber()
+	}
+	return
+}
+
+func (a *archive) Move(pathname string, index int) (err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if outer, inner, nested := splitNestedPath(pathname); nested {
+		err = a.updateNested(outer, false, func(ia *archive) (err error) {
+			return ia.Move(inner, index)
+		})
+		return
+	}
+
+	from := a.indexOf(pathname)
+	if from < 0 {
+		err = ErrNotFound
+		return
+	} else if index < 0 || index >= len(a.entries) {
+		err = ErrIndexOutOfRange
+		return
+	}
+	a.move(from, index)
+	a.renumber()
+	return
+}
+
+func (a *archive) Sort(fn CompareFn) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if fn == nil {
+		fn = LexicalOrder
+	}
+
+	type sortable struct {
+		item   *entry
+		cloned *entry
+	}
+	list := make([]sortable, len(a.entries))
+	for idx, item := range a.entries {
+		list[idx] = sortable{item: item, cloned: item.clone()}
+	}
+	slices.SortStableFunc(list, func(x, y sortable) int {
+		return fn(x.cloned, y.cloned)
+	})
+
+	for idx, sorted := range list {
+		if a.entries[idx] != sorted.item {
+			a.entries[idx] = sorted.item
+			a.report(sorted.item.GetPathname(), OpMoved, idx)
+		}
+	}
+	a.renumber()
+}
+
+// indexOf returns the index of the pathname entry, or -1 if not present
+func (a *archive) indexOf(pathname string) (index int) {
+	if this, present := a.lookup[pathname]; present {
+		return slices.Index(a.entries, this)
+	}
+	return -1
+}
+
+// move repositions the entry at the from index to be at the to index, the
+// caller is responsible for calling renumber
+func (a *archive) move(from, to int) {
+	if from == to {
+		return
+	}
+	item := a.entries[from]
+	a.entries = slices.Delete(a.entries, from, from+1)
+	a.entries = slices.Insert(a.entries, to, item)
+	a.report(item.GetPathname(), OpMoved, to)
+}
+
+// renumber updates the line number of each entry, and the last line of this
+// archive, to match the output of String
+func (a *archive) renumber() {
+	nodes, lines := a.syntax()
+	idx := 0
+	for _, node := range nodes {
+		if node.Kind == SyntaxHeader {
+			// there is exactly one header for each entry, in order
+			a.entries[idx].line = node.Line
+			idx += 1
+		}
+	}
+	a.lastLine = lines
+}
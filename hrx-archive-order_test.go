@@ -0,0 +1,151 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tOrderHRX = `<===> file10.txt
+ten
+<===>
+two comment
+<===> file2.txt
+two
+<===> b/
+<===> a/file.txt
+a file
+<===> A.txt
+upper
+`
+
+// tLines returns the line numbers of all entries and the last line of the
+// archive given
+func tLines(a Archive) (lines []int, last int) {
+	for _, item := range a.(*archive).entries {
+		lines = append(lines, item.line)
+	}
+	return lines, a.(*archive).lastLine
+}
+
+// tCheckLines asserts the line numbers of the archive given match those of
+// parsing the archive String output
+func tCheckLines(a Archive) {
+	reparsed, err := ParseData("reparsed.hrx", a.String())
+	So(err, ShouldBeNil)
+	So(reparsed.List(), ShouldEqual, a.List())
+	lines, last := tLines(a)
+	expected, expectedLast := tLines(reparsed)
+	So(lines, ShouldEqual, expected)
+	So(last, ShouldEqual, expectedLast)
+}
+
+func TestOrder(t *testing.T) {
+	Convey("Ordering entries", t, func() {
+		a, err := ParseData("order.hrx", tOrderHRX)
+		So(err, ShouldBeNil)
+
+		var moved []string
+		a.SetReporter(func(archive, pathname, note string, argv ...interface{}) {
+			if note == OpMoved {
+				moved = append(moved, pathname)
+			}
+		})
+
+		Convey("InsertBefore and InsertAfter", func() {
+			So(a.InsertBefore("file2.txt", "file1.txt", "one", "one comment"), ShouldBeNil)
+			So(a.InsertAfter("file2.txt", "file3.txt", "three", ""), ShouldBeNil)
+			So(a.InsertAfter("A.txt", "Z.txt", "last\n", ""), ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"file10.txt", "file1.txt", "file2.txt", "file3.txt", "b/", "a/file.txt", "A.txt", "Z.txt"})
+			So(moved, ShouldEqual, []string{"file1.txt", "file3.txt"})
+			_, comment, _ := a.Get("file2.txt")
+			So(comment, ShouldEqual, "two comment\n")
+			tCheckLines(a)
+
+			So(a.InsertBefore("missing.txt", "new.txt", "", ""), ShouldEqual, ErrNotFound)
+			So(a.InsertBefore("A.txt", "file1.txt", "", ""), ShouldWrap, ErrDuplicatePath)
+		})
+
+		Convey("Move", func() {
+			So(a.Move("A.txt", 0), ShouldBeNil)
+			So(a.Move("file2.txt", 4), ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"A.txt", "file10.txt", "b/", "a/file.txt", "file2.txt"})
+			_, comment, _ := a.Get("file2.txt")
+			So(comment, ShouldEqual, "two comment\n")
+			tCheckLines(a)
+
+			So(a.Move("missing.txt", 0), ShouldEqual, ErrNotFound)
+			So(a.Move("A.txt", 5), ShouldEqual, ErrIndexOutOfRange)
+			So(a.Move("A.txt", -1), ShouldEqual, ErrIndexOutOfRange)
+		})
+
+		Convey("Sort", func() {
+			a.Sort(nil)
+			So(a.List(), ShouldEqual, []string{"A.txt", "a/file.txt", "b/", "file10.txt", "file2.txt"})
+			tCheckLines(a)
+
+			a.Sort(NaturalOrder)
+			So(a.List(), ShouldEqual, []string{"A.txt", "a/file.txt", "b/", "file2.txt", "file10.txt"})
+			_, comment, _ := a.Get("file2.txt")
+			So(comment, ShouldEqual, "two comment\n")
+			tCheckLines(a)
+
+			a.Sort(DirsFirst(NaturalOrder))
+			So(a.List(), ShouldEqual, []string{"a/file.txt", "b/", "A.txt", "file2.txt", "file10.txt"})
+			tCheckLines(a)
+
+			a.Delete("A.txt")
+			tCheckLines(a)
+			a.Delete("file10.txt")
+			tCheckLines(a)
+		})
+
+		Convey("nested", func() {
+			b, err := ParseData("outer.hrx", "<===> inner.hrx\n<====> z.txt\nz\n<====> y.txt\ny\n")
+			So(err, ShouldBeNil)
+			So(b.Move("inner.hrx//y.txt", 0), ShouldBeNil)
+			So(b.InsertAfter("inner.hrx//y.txt", "inner.hrx//x.txt", "x", ""), ShouldBeNil)
+			nested, err := b.ParseHRX("inner.hrx")
+			So(err, ShouldBeNil)
+			So(nested.List(), ShouldEqual, []string{"y.txt", "x.txt", "z.txt"})
+			So(b.InsertAfter("inner.hrx//y.txt", "w.txt", "", ""), ShouldEqual, ErrNotFound)
+		})
+
+		Convey("comparators", func() {
+			for _, test := range []struct {
+				a, b  string
+				order int
+			}{
+				{"file2", "file10", -1},
+				{"file010", "file10", 1},
+				{"file10", "file10", 0},
+				{"a10b2", "a10b10", -1},
+				{"b", "a1", 1},
+			} {
+				order := compareNatural(test.a, test.b)
+				switch {
+				case test.order < 0:
+					So(order, ShouldBeLessThan, 0)
+				case test.order > 0:
+					So(order, ShouldBeGreaterThan, 0)
+				default:
+					So(order, ShouldEqual, 0)
+				}
+			}
+		})
+	})
+}
@@ -15,45 +15,85 @@
 package hrx
 
 import (
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 func parseData[V string | []byte | []rune](filename string, data V) (hrx *archive, err error) {
-	a := newArchive(filename, "")
-	if err = a.parseString(string(data)); err != nil {
-		return
-	}
-	hrx = a
+	return parseDataWith(filename, data, nil)
+}
+
+func parseDataWith[V string | []byte | []rune](filename string, data V, options *ParseOptions) (hrx *archive, err error) {
+	a := newParsing(filename, options)
+	hrx, err = a.parsed(a.parseString(string(data)))
 	return
 }
 
 func parseReader(filename string, reader io.Reader) (hrx *archive, err error) {
-	a := newArchive(filename, "")
-	if err = a.parseReader(reader); err != nil {
-		return
-	}
-	hrx = a
+	return parseReaderWith(filename, reader, nil)
+}
+
+func parseReaderWith(filename string, reader io.Reader, options *ParseOptions) (hrx *archive, err error) {
+	a := newParsing(filename, options)
+	hrx, err = a.parsed(a.parseReader(reader))
 	return
 }
 
 func parseFile(path string) (hrx *archive, err error) {
+	return parseFileWith(path, nil)
+}
+
+func parseFileWith(path string, options *ParseOptions) (hrx *archive, err error) {
 	var fh *os.File
 	if fh, err = os.OpenFile(path, os.O_RDONLY, 0640); err == nil {
 		defer fh.Close()
-		a := newArchive(filepath.Base(path), "")
-		if err = a.parseReader(fh); err == nil {
+		a := newParsing(filepath.Base(path), options)
+		hrx, err = a.parsed(a.parseReader(fh))
+	}
+	return
+}
+
+// newParsing returns a new archive configured for parsing with the options
+// given
+func newParsing(filename string, options *ParseOptions) (a *archive) {
+	a = newArchive(filename, "")
+	a.lenient = options != nil && options.Lenient
+	return
+}
+
+// parsed completes parsing, returning this archive when there is no error or
+// when parsing leniently, along with all the errors recorded in line order
+func (a *archive) parsed(err error) (hrx *archive, errs error) {
+	if !a.lenient {
+		if err == nil {
 			hrx = a
 		}
+		return hrx, err
 	}
-	return
+
+	slices.SortStableFunc(a.errs, func(x, y error) int {
+		xe, _ := AsError(x)
+		ye, _ := AsError(y)
+		if xe.Line != ye.Line {
+			return xe.Line - ye.Line
+		}
+		return xe.Column - ye.Column
+	})
+	errs = errors.Join(a.errs...)
+	a.lenient, a.errs = false, nil
+	if a.boundary == 0 {
+		a.boundary = DefaultBoundary
+	}
+	return a, errs
 }
 
 func (a *archive) parseString(input string) (err error) {
 	if strings.TrimSpace(input) == "" {
-		err = a.error(0, 0, ErrEmptyArchive, ErrMalformedInput)
+		err = a.fail(a.error(0, ErrEmptyArchive, ErrMalformedInput))
 		return
 	}
 	err = a.parseReader(strings.NewReader(input))
@@ -62,10 +102,17 @@ func (a *archive) parseString(input string) (err error) {
 
 func (a *archive) parseReader(reader io.Reader) (err error) {
 
+	var skipping bool
 	var this *entry
 	for s := NewScanner(reader); s.Scan(); {
 
 		content, line, boundary, pathname, header, sErr := s.Get()
+		if this == nil && content == "" {
+			// empty input
+			break
+		}
+		offset, column := s.Position()
+		src := &headerSource{line: line, offset: offset, text: strings.TrimSuffix(content, "\n"), boundary: boundary, column: column}
 		a.lastLine = line
 		if a.parseReaderGetBoundaryCheck(this, line, header) {
 			a.boundary = boundary
@@ -74,30 +121,28 @@ func (a *archive) parseReader(reader io.Reader) (err error) {
 		if this == nil {
 			// this is the first entry
 			if !header {
-				// this is not a valid .hrx file
-				return a.error(line, 0, ErrBadArchiveHeader, ErrMalformedInput)
-			}
-
-			// validate path components, path characters validated during Scan()
-			if eee := checkPathComponents(pathname); eee != nil {
-				return a.error(line, 0, eee, ErrBadFileEntry)
+				// this is not a valid .hrx file, lenient parsing skips to the
+				// first header
+				if !skipping {
+					if err = a.fail(src.error(a.filename, line, 1, ErrBadArchiveHeader, ErrMalformedInput)); err != nil {
+						return
+					}
+					skipping = true
+				}
+				continue
 			}
 
-			this = newEntry(line, boundary, pathname, "", sErr)
+			this = a.parseReaderNewEntry(src, content, pathname, sErr)
 			continue
 		}
 
 		// is this a new entry?
 		if header {
-			if !this.hrx || boundary == a.boundary {
-				// new entry, and not one within an embedded .hrx,
-				// stack this and make a new entry
+			if boundary == a.boundary {
+				// new entry, lines with any other boundary size are
+				// part of the body, stack this and make a new entry
 				a.entries = append(a.entries, this)
-				this = newEntry(line, boundary, pathname, "", sErr)
-				if this.IsFile() || this.IsComment() {
-					body := ""
-					this.body = &body
-				}
+				this = a.parseReaderNewEntry(src, content, pathname, sErr)
 				continue
 			}
 		}
@@ -105,21 +150,45 @@ func (a *archive) parseReader(reader io.Reader) (err error) {
 		// this is not a new entry
 		this.appendBody(content)
 		if _, nextBoundary, _, nextIsHeader, _, ok := s.Peek(); ok && a.parseReaderPeekTrimNLCheck(this, nextBoundary, nextIsHeader) {
-			// last newline is a part of the next boundary
+			// last newline is a part of the next boundary, recorded as the
+			// separator of this entry
 			*this.body = strings.TrimSuffix(*this.body, "\n")
+			this.sep = true
 		}
 	}
 
 	if this != nil {
 		a.entries = append(a.entries, this)
+	} else {
+		if !skipping {
+			err = a.fail(a.error(0, ErrEmptyArchive, ErrMalformedInput))
+		}
+		return
 	}
 
 	err = a.finalize()
 	return
 }
 
+// parseReaderNewEntry returns a new entry for the header line given, with an
+// empty body for files and comments so that empty bodies are preserved
+func (a *archive) parseReaderNewEntry(src *headerSource, content, pathname string, sErr error) (this *entry) {
+	this = newEntry(src.line, src.boundary, pathname, "", sErr)
+	this.src = src
+	this.header = content
+	// validate path components, path characters validated during Scan()
+	if idx, eee := checkPathComponentsAt(pathname); eee != nil {
+		this.invalid = src.error(a.filename, src.line, src.pathColumn(idx), eee, ErrBadFileEntry)
+	}
+	if this.IsFile() || this.IsComment() {
+		body := ""
+		this.body = &body
+	}
+	return
+}
+
 func (a *archive) parseReaderGetBoundaryCheck(this *entry, line int, header bool) (ok bool) {
-	return a.boundary == 0 && this == nil && line == 1 && header
+	return a.boundary == 0 && this == nil && header
 }
 
 func (a *archive) parseReaderPeekTrimNLCheck(this *entry, nextBoundary int, nextIsHeader bool) (trim bool) {
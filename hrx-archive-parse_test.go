@@ -0,0 +1,119 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tLenientHRX = `stray line
+<===> one.txt
+one
+<===> one.txt
+duplicate
+<===> dir/
+contents
+<===> bad:name.txt
+bad
+<===> ../escape.txt
+escape
+<===>
+first comment
+<===>
+second comment
+<===> one.txt/child.txt
+child
+<===> two.txt
+two
+`
+
+// tErrors returns each *Error joined within the error given
+func tErrors(err error) (list []*Error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, ee := range joined.Unwrap() {
+			if e, ok := AsError(ee); ok {
+				list = append(list, e)
+			}
+		}
+	}
+	return
+}
+
+func TestParseOptions(t *testing.T) {
+	Convey("Lenient parsing", t, func() {
+
+		Convey("strict parsing stops at the first error", func() {
+			a, err := ParseDataWith("lenient.hrx", tLenientHRX, nil)
+			So(a, ShouldBeNil)
+			So(err, ShouldWrap, ErrBadArchiveHeader)
+			_, err = ParseData("lenient.hrx", strings.TrimPrefix(tLenientHRX, "stray line\n"))
+			e, ok := AsError(err)
+			So(ok, ShouldBeTrue)
+			So(e.Line, ShouldEqual, 3)
+			So(err, ShouldWrap, ErrDuplicatePath)
+		})
+
+		Convey("lenient parsing reports every error", func() {
+			a, err := ParseDataWith("lenient.hrx", tLenientHRX, &ParseOptions{Lenient: true})
+			So(a, ShouldNotBeNil)
+			So(err, ShouldNotBeNil)
+			list := tErrors(err)
+			var found []string
+			for _, e := range list {
+				found = append(found, e.Source)
+			}
+			So(found, ShouldEqual, []string{
+				"stray line",
+				"<===> one.txt",
+				"<===> dir/",
+				"<===> bad:name.txt",
+				"<===> ../escape.txt",
+				"<===>",
+				"<===> one.txt/child.txt",
+			})
+			So(errors.Is(list[1], ErrDuplicatePath), ShouldBeTrue)
+			So(errors.Is(list[2], ErrDirectoryHasContents), ShouldBeTrue)
+			So(errors.Is(list[3], ErrContainsColon), ShouldBeTrue)
+			So(errors.Is(list[4], ErrContainsRelPath), ShouldBeTrue)
+			So(errors.Is(list[5], ErrSequentialComments), ShouldBeTrue)
+			So(errors.Is(list[6], ErrFileAsParentDir), ShouldBeTrue)
+			So(errors.Is(err, ErrDuplicatePath), ShouldBeTrue)
+
+			So(a.List(), ShouldEqual, []string{"one.txt", "dir/", "two.txt"})
+			body, _, _ := a.Get("one.txt")
+			So(body, ShouldEqual, "one")
+			So(a.Entry("dir/").GetBody(), ShouldEqual, "")
+			So(a.GetBoundary(), ShouldEqual, 3)
+		})
+
+		Convey("lenient parsing of empty and headerless input", func() {
+			for _, input := range []string{"", "  \n", "no headers\nat all\n"} {
+				a, err := ParseDataWith("empty.hrx", input, &ParseOptions{Lenient: true})
+				So(a, ShouldNotBeNil)
+				So(a.Len(), ShouldEqual, 0)
+				So(a.GetBoundary(), ShouldEqual, DefaultBoundary)
+				So(tErrors(err), ShouldHaveLength, 1)
+			}
+
+			a, err := ParseReaderWith("reader.hrx", strings.NewReader(""), nil)
+			So(a, ShouldBeNil)
+			So(err, ShouldWrap, ErrEmptyArchive)
+		})
+	})
+}
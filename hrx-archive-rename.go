@@ -0,0 +1,219 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+)
+
+func (a *archive) Rename(oldPath, newPath string) (err error) {
+	oldOuter, oldInner, oldNested := splitNestedPath(oldPath)
+	newOuter, newInner, newNested := splitNestedPath(newPath)
+	if oldNested && newNested && oldOuter == newOuter {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		err = a.updateNested(oldOuter, false, func(ia *archive) (err error) {
+			return ia.Rename(oldInner, newInner)
+		})
+		return
+	} else if oldNested || newNested {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		err = a.moveEntry(oldPath, newPath)
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	this, ok := a.lookup[oldPath]
+	if !ok {
+		err = ErrNotFound
+		return
+	} else if oldPath == newPath {
+		return
+	} else if this.IsDir() != strings.HasSuffix(newPath, "/") {
+		err = a.error(this.line, ErrNotADirectory, ErrBadFileEntry)
+		return
+	} else if ee := checkPathname(strings.TrimSuffix(newPath, "/")); ee != nil || newPath == "" {
+		err = a.error(this.line, ee, ErrBadFileEntry)
+		return
+	}
+
+	err = a.renameEntries(map[string]string{oldPath: newPath})
+	return
+}
+
+func (a *archive) MoveDir(oldPrefix, newPrefix string) (err error) {
+	oldPrefix = strings.TrimSuffix(oldPrefix, "/") + "/"
+	newPrefix = strings.TrimSuffix(newPrefix, "/") + "/"
+
+	oldOuter, oldInner, oldNested := splitNestedPath(oldPrefix)
+	newOuter, newInner, newNested := splitNestedPath(newPrefix)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if oldNested && newNested && oldOuter == newOuter {
+		err = a.updateNested(oldOuter, false, func(ia *archive) (err error) {
+			return ia.MoveDir(oldInner, newInner)
+		})
+		return
+	} else if oldNested || newNested {
+		err = ErrNotADirectory
+		return
+	} else if ee := checkPathname(strings.TrimSuffix(newPrefix, "/")); ee != nil || newPrefix == "/" {
+		err = a.error(a.lastLine, ee, ErrBadFileEntry)
+		return
+	}
+
+	renames := make(map[string]string)
+	for _, item := range a.entries {
+		if rest, ok := strings.CutPrefix(item.GetPathname(), oldPrefix); ok {
+			renames[item.GetPathname()] = newPrefix + rest
+		} else if item.GetPathname() == oldPrefix {
+			renames[oldPrefix] = newPrefix
+		}
+	}
+	if len(renames) == 0 {
+		err = ErrNotFound
+		return
+	} else if oldPrefix == newPrefix {
+		return
+	}
+
+	err = a.renameEntries(renames)
+	return
+}
+
+// renameEntries validates all entries with the renames given applied and if
+// there are no collisions, updates each renamed entry in place
+func (a *archive) renameEntries(renames map[string]string) (err error) {
+	tracker := newPathTracker()
+	for _, item := range a.entries {
+		if item.IsComment() {
+			continue
+		}
+		pathname := item.GetPathname()
+		if renamed, ok := renames[pathname]; ok {
+			pathname = renamed
+		}
+		if ee := tracker.add(pathname); ee != nil {
+			return a.error(item.line, nil, ee)
+		}
+	}
+
+	var renamed []*entry
+	for _, item := range a.entries {
+		if _, ok := renames[item.GetPathname()]; ok && !item.IsComment() {
+			delete(a.lookup, item.GetPathname())
+			renamed = append(renamed, item)
+		}
+	}
+	for _, item := range renamed {
+		original, pathname := item.GetPathname(), renames[item.GetPathname()]
+		item.pathname = &pathname
+		item.hrx = strings.HasSuffix(pathname, ".hrx")
+		a.lookup[pathname] = item
+		a.report(original, OpRenamed, pathname)
+	}
+	return
+}
+
+// moveEntry moves an entry between archive levels, appending the entry to
+// the destination archive
+func (a *archive) moveEntry(oldPath, newPath string) (err error) {
+	var level *archive
+	var name string
+	if level, name, err = a.nestedLevel(oldPath); err != nil {
+		err = ErrNotFound
+		return
+	}
+	this, ok := level.lookup[name]
+	if !ok {
+		err = ErrNotFound
+		return
+	} else if this.IsDir() != strings.HasSuffix(newPath, "/") {
+		err = ErrNotADirectory
+		return
+	} else if strings.HasPrefix(newPath, oldPath+NestedSeparator) {
+		// an archive cannot be moved into itself
+		err = level.error(this.line, nil, ErrFileAsParentDir)
+		return
+	} else if err = a.checkDestination(newPath); err != nil {
+		return
+	}
+
+	if err = a.set(newPath, this.GetBody(), this.GetComment()); err == nil {
+		a.delete(oldPath)
+		a.report(oldPath, OpRenamed, newPath)
+	}
+	return
+}
+
+// nestedLevel returns the archive containing the entry at the nested address
+// given, along with the pathname of the entry within that archive
+func (a *archive) nestedLevel(path string) (level *archive, pathname string, err error) {
+	level, pathname = a, path
+	for outer, inner, nested := splitNestedPath(pathname); nested; outer, inner, nested = splitNestedPath(pathname) {
+		if level, err = level.getNested(outer); err != nil {
+			return
+		}
+		pathname = inner
+	}
+	return
+}
+
+// checkDestination validates the nested address given as the pathname of a
+// new entry, at each level the pathname must be allowed and must not collide
+// with any existing entries. Levels which do not exist yet are created empty
+// by Set and only need their pathnames checked
+func (a *archive) checkDestination(path string) (err error) {
+	level, pathname := a, path
+	for level != nil {
+		outer, inner, nested := splitNestedPath(pathname)
+		name := pathname
+		if nested {
+			name = outer
+		}
+		if ee := checkPathname(strings.TrimSuffix(name, "/")); ee != nil || name == "" {
+			return level.error(level.lastLine, ee, ErrBadFileEntry)
+		}
+
+		if _, present := level.lookup[name]; present && nested {
+			if level, err = level.getNested(name); err != nil {
+				return
+			}
+			pathname = inner
+			continue
+		}
+
+		tracker := newPathTracker()
+		for _, item := range level.entries {
+			if !item.IsComment() {
+				_ = tracker.add(item.GetPathname())
+			}
+		}
+		if ee := tracker.add(name); ee != nil {
+			return level.error(level.lastLine, nil, ee)
+		}
+		level, pathname = nil, inner
+		if nested {
+			// the remainder is added to a new, empty archive
+			level = newArchive(name, "")
+		}
+	}
+	return
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tRenameHRX = `<===> one.txt
+one
+<===>
+two comment
+<===> dir/two.txt
+two
+<===> dir/sub/three.txt
+three
+<===> dir/empty/
+<===> nested.hrx
+<====> inner.txt
+inner
+<===> last.txt
+last
+`
+
+func TestRename(t *testing.T) {
+	Convey("Rename and MoveDir", t, func() {
+		a, err := ParseData("rename.hrx", tRenameHRX)
+		So(err, ShouldBeNil)
+
+		var reported []string
+		a.SetReporter(func(archive, pathname, note string, argv ...interface{}) {
+			if note == OpRenamed {
+				reported = append(reported, pathname+" -> "+argv[0].(string))
+			}
+		})
+
+		Convey("Rename", func() {
+			So(a.Rename("dir/two.txt", "dir/2.txt"), ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"one.txt", "dir/2.txt", "dir/sub/three.txt", "dir/empty/", "nested.hrx", "last.txt"})
+			body, comment, ok := a.Get("dir/2.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "two")
+			So(comment, ShouldEqual, "two comment\n")
+			So(reported, ShouldEqual, []string{"dir/two.txt -> dir/2.txt"})
+			So(a.String(), ShouldEqual, `<===> one.txt
+one
+<===>
+two comment
+<===> dir/2.txt
+two
+<===> dir/sub/three.txt
+three
+<===> dir/empty/
+<===> nested.hrx
+<====> inner.txt
+inner
+<===> last.txt
+last
+`)
+			So(a.Entry("dir/2.txt").(*entry).line, ShouldEqual, 5)
+
+			So(a.Rename("missing.txt", "other.txt"), ShouldEqual, ErrNotFound)
+			So(errors.Is(a.Rename("one.txt", "last.txt"), ErrDuplicatePath), ShouldBeTrue)
+			So(errors.Is(a.Rename("one.txt", "dir/2.txt/file"), ErrFileAsParentDir), ShouldBeTrue)
+			So(errors.Is(a.Rename("one.txt", "dir"), ErrFileAsParentDir), ShouldBeTrue)
+			So(errors.Is(a.Rename("one.txt", "../one.txt"), ErrContainsRelPath), ShouldBeTrue)
+			So(errors.Is(a.Rename("dir/empty/", "dir/full"), ErrNotADirectory), ShouldBeTrue)
+			So(a.List()[0], ShouldEqual, "one.txt")
+		})
+
+		Convey("MoveDir", func() {
+			So(a.MoveDir("dir", "moved/here/"), ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"one.txt", "moved/here/two.txt", "moved/here/sub/three.txt", "moved/here/empty/", "nested.hrx", "last.txt"})
+			_, comment, _ := a.Get("moved/here/two.txt")
+			So(comment, ShouldEqual, "two comment\n")
+			So(reported, ShouldHaveLength, 3)
+
+			So(a.MoveDir("missing", "other"), ShouldEqual, ErrNotFound)
+			So(errors.Is(a.MoveDir("moved/here/sub", "last.txt"), ErrFileAsParentDir), ShouldBeTrue)
+			So(a.Entry("moved/here/sub/three.txt"), ShouldNotBeNil)
+		})
+
+		Convey("nested", func() {
+			So(a.Rename("nested.hrx//inner.txt", "nested.hrx//renamed.txt"), ShouldBeNil)
+			body, _, ok := a.Get("nested.hrx//renamed.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "inner")
+
+			So(a.Rename("one.txt", "nested.hrx//one.txt"), ShouldBeNil)
+			So(a.Entry("one.txt"), ShouldBeNil)
+			body, _, ok = a.Get("nested.hrx//one.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "one")
+
+			So(a.Rename("nested.hrx//renamed.txt", "inner.txt"), ShouldBeNil)
+			body, _, ok = a.Get("inner.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "inner")
+			So(errors.Is(a.Rename("last.txt", "nested.hrx//one.txt"), ErrDuplicatePath), ShouldBeTrue)
+		})
+
+		Convey("nested destinations are validated", func() {
+			before := a.String()
+			So(errors.Is(a.Rename("nested.hrx//inner.txt", "../evil"), ErrContainsRelPath), ShouldBeTrue)
+			So(errors.Is(a.Rename("one.txt", "nested.hrx//inner.txt/sub"), ErrFileAsParentDir), ShouldBeTrue)
+			So(errors.Is(a.Rename("one.txt", "nested.hrx//inner.txt"), ErrDuplicatePath), ShouldBeTrue)
+			So(errors.Is(a.Rename("one.txt", "last.txt/new.hrx//one.txt"), ErrFileAsParentDir), ShouldBeTrue)
+			So(errors.Is(a.Rename("one.txt", "new.hrx//a:b"), ErrContainsColon), ShouldBeTrue)
+			So(errors.Is(a.Rename("nested.hrx", "nested.hrx//self.hrx"), ErrFileAsParentDir), ShouldBeTrue)
+			So(a.String(), ShouldEqual, before)
+
+			So(a.Rename("one.txt", "new.hrx//one.txt"), ShouldBeNil)
+			b, err := ParseData("renamed.hrx", a.String())
+			So(err, ShouldBeNil)
+			body, _, ok := b.Get("new.hrx//one.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "one")
+		})
+	})
+}
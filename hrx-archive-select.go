@@ -0,0 +1,67 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+func (a *archive) Select(fn SelectFn) (pathnames []string) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	for _, item := range a.selected(fn) {
+		pathnames = append(pathnames, item.GetPathname())
+	}
+	return
+}
+
+func (a *archive) Filter(fn SelectFn) (filtered Archive) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	b := newArchive(a.filename, "")
+	b.boundary = a.boundary
+	b.lastLine = a.lastLine
+	if a.comment != nil {
+		comment := *a.comment
+		b.comment = &comment
+	}
+	for _, item := range a.selected(fn) {
+		cloned := item.clone()
+		b.entries = append(b.entries, cloned)
+		b.lookup[cloned.GetPathname()] = cloned
+	}
+	return b
+}
+
+func (a *archive) ExtractSelected(destination string, fn SelectFn) (err error) {
+	return a.extractTo(destination, &ExtractOptions{Select: fn})
+}
+
+func (a *archive) DeleteSelected(fn SelectFn) (pathnames []string) {
+	pathnames = a.Select(fn)
+	for _, pathname := range pathnames {
+		a.Delete(pathname)
+	}
+	return
+}
+
+// selected returns all entries with pathnames which are selected by the fn
+// given, a nil fn selects all entries
+func (a *archive) selected(fn SelectFn) (selected []*entry) {
+	for _, item := range a.entries {
+		if item.GetPathname() == "" {
+			continue
+		} else if fn == nil || fn(item.clone()) {
+			selected = append(selected, item)
+		}
+	}
+	return
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clPath "github.com/go-corelibs/path"
+	"github.com/go-corelibs/tdata"
+)
+
+const tSelectHRX = `<===> README.md
+readme
+<===> testdata/one.golden
+one
+<===> testdata/sub/two.golden
+two
+<===> testdata/sub/two.txt
+two text
+<===> testdata/empty/
+<===> main.golden
+main
+`
+
+func TestSelect(t *testing.T) {
+	Convey("Glob and Select", t, func() {
+
+		Convey("MatchGlob", func() {
+			for _, test := range []struct {
+				pattern  string
+				pathname string
+				matched  bool
+			}{
+				{"file.txt", "file.txt", true},
+				{"[bad", "[bad", true},
+				{"[bad", "b", false},
+				{"*.txt", "file.txt", true},
+				{"*.txt", "dir/file.txt", false},
+				{"**/*.txt", "file.txt", true},
+				{"**/*.txt", "dir/sub/file.txt", true},
+				{"dir/**", "dir/sub/file.txt", true},
+				{"dir/**/file.txt", "dir/file.txt", true},
+				{"dir/**/file.txt", "other/file.txt", false},
+				{"dir/*", "dir/sub/", true},
+				{"dir/", "dir/", true},
+				{"dir/", "dir/sub/file.txt", true},
+				{"dir/", "dir", false},
+				{"dir/", "directory/file.txt", false},
+				{"d?r/", "dir/file.txt", true},
+				{"sub/", "dir/sub/file.txt", false},
+				{"**/sub/", "dir/sub/file.txt", true},
+			} {
+				So(MatchGlob(test.pattern, test.pathname), ShouldEqual, test.matched)
+			}
+		})
+
+		a, err := ParseData("select.hrx", tSelectHRX)
+		So(err, ShouldBeNil)
+
+		Convey("Select and Filter", func() {
+			So(a.Select(nil), ShouldEqual, a.List())
+			So(a.Select(Glob("**/*.golden")), ShouldEqual, []string{"testdata/one.golden", "testdata/sub/two.golden", "main.golden"})
+			So(a.Select(Glob("testdata/sub/")), ShouldEqual, []string{"testdata/sub/two.golden", "testdata/sub/two.txt"})
+			So(a.Select(func(e Entry) bool { return e.IsDir() }), ShouldEqual, []string{"testdata/empty/"})
+
+			f := a.Filter(Glob("testdata/"))
+			So(f.List(), ShouldEqual, []string{"testdata/one.golden", "testdata/sub/two.golden", "testdata/sub/two.txt", "testdata/empty/"})
+			So(f.Set("testdata/one.golden", "changed", ""), ShouldBeNil)
+			body, _, _ := a.Get("testdata/one.golden")
+			So(body, ShouldEqual, "one")
+			var buf strings.Builder
+			So(f.ToTxtar(&buf), ShouldBeNil)
+			So(buf.String(), ShouldStartWith, "-- testdata/one.golden --\nchanged\n")
+		})
+
+		Convey("DeleteSelected", func() {
+			So(a.DeleteSelected(Glob("**/*.golden")), ShouldEqual, []string{"testdata/one.golden", "testdata/sub/two.golden", "main.golden"})
+			So(a.List(), ShouldEqual, []string{"README.md", "testdata/sub/two.txt", "testdata/empty/"})
+			So(a.DeleteSelected(Glob("nothing")), ShouldBeEmpty)
+		})
+
+		Convey("ExtractTo and ExtractSelected", func() {
+			tempdir, ee := tdata.NewTempData("", "hrx-lib.Select.*")
+			So(ee, ShouldBeNil)
+			defer tempdir.Destroy()
+			So(a.ExtractTo(tempdir.Join("glob"), "testdata/**/*.golden"), ShouldBeNil)
+			So(clPath.IsFile(tempdir.Join("glob", "testdata", "sub", "two.golden")), ShouldBeTrue)
+			So(clPath.Exists(tempdir.Join("glob", "testdata", "sub", "two.txt")), ShouldBeFalse)
+			So(clPath.Exists(tempdir.Join("glob", "main.golden")), ShouldBeFalse)
+
+			So(a.ExtractSelected(tempdir.Join("selected"), func(e Entry) bool { return e.IsDir() }), ShouldBeNil)
+			entries, ee := os.ReadDir(tempdir.Join("selected", "testdata"))
+			So(ee, ShouldBeNil)
+			So(entries, ShouldHaveLength, 1)
+			So(entries[0].Name(), ShouldEqual, "empty")
+		})
+
+		Convey("ExtractWith", func() {
+			tempdir, ee := tdata.NewTempData("", "hrx-lib.ExtractWith.*")
+			So(ee, ShouldBeNil)
+			defer tempdir.Destroy()
+
+			var reported [][]interface{}
+			a.SetReporter(func(archive, pathname, note string, argv ...interface{}) {
+				if note != OpSkipped {
+					reported = append(reported, append([]interface{}{pathname, note}, argv...))
+				}
+			})
+
+			So(a.ExtractWith(tempdir.Join("prefix"), &ExtractOptions{Prefix: "testdata/sub/"}), ShouldBeNil)
+			So(clPath.IsFile(tempdir.Join("prefix", "two.golden")), ShouldBeTrue)
+			So(clPath.IsFile(tempdir.Join("prefix", "two.txt")), ShouldBeTrue)
+			So(clPath.Exists(tempdir.Join("prefix", "one.golden")), ShouldBeFalse)
+			So(reported, ShouldHaveLength, 2)
+			So(reported[0], ShouldEqual, []interface{}{"testdata/sub/two.golden", OpExtracted, tempdir.Join("prefix", "two.golden")})
+
+			So(a.ExtractWith(tempdir.Join("strip"), &ExtractOptions{
+				Select: Glob("**/*.golden"),
+				Strip:  1,
+				Rename: func(pathname string) string {
+					return strings.TrimSuffix(pathname, ".golden") + ".txt"
+				},
+			}), ShouldBeNil)
+			So(clPath.IsFile(tempdir.Join("strip", "one.txt")), ShouldBeTrue)
+			So(clPath.IsFile(tempdir.Join("strip", "sub", "two.txt")), ShouldBeTrue)
+			So(clPath.Exists(tempdir.Join("strip", "main.txt")), ShouldBeFalse)
+
+			err := a.ExtractWith(tempdir.Join("invalid"), &ExtractOptions{
+				Rename: func(pathname string) string { return "../" + pathname },
+			})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrContainsRelPath.Error())
+			So(clPath.Exists(tempdir.Join("README.md")), ShouldBeFalse)
+		})
+
+	})
+}
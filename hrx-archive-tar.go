@@ -0,0 +1,212 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+// paxComment is the PAX record used to store HRX comments within tar archives
+const paxComment = "comment"
+
+// TarOptions configures the tar headers produced by Archive.ToTar
+type TarOptions struct {
+	// FileMode is the permissions of files, defaults to DefaultFileMode
+	FileMode os.FileMode
+	// DirMode is the permissions of directories, defaults to
+	// path.DefaultPathPerms
+	DirMode os.FileMode
+	// ModTime is the modification time of all entries, defaults to the
+	// unix epoch
+	ModTime time.Time
+}
+
+func (o *TarOptions) fileMode() os.FileMode {
+	if o == nil || o.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return o.FileMode
+}
+
+func (o *TarOptions) dirMode() os.FileMode {
+	if o == nil || o.DirMode == 0 {
+		return clPath.DefaultPathPerms
+	}
+	return o.DirMode
+}
+
+func (o *TarOptions) modTime() time.Time {
+	if o == nil || o.ModTime.IsZero() {
+		return time.Unix(0, 0)
+	}
+	return o.ModTime
+}
+
+func (a *archive) ToTar(w io.Writer, options *TarOptions) (err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	tw := tar.NewWriter(w)
+	if a.comment != nil {
+		if err = tw.WriteHeader(&tar.Header{
+			Typeflag:   tar.TypeXGlobalHeader,
+			Name:       "pax_global_header",
+			PAXRecords: map[string]string{paxComment: *a.comment},
+		}); err != nil {
+			return
+		}
+	}
+
+	written := make(map[string]struct{})
+	writeDir := func(pathname string, item *entry) (err error) {
+		if _, present := written[pathname]; present {
+			return
+		}
+		written[pathname] = struct{}{}
+		defer a.report(pathname, OpExported, pathname)
+		hdr := &tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     pathname,
+			Mode:     int64(options.dirMode().Perm()),
+			ModTime:  options.modTime(),
+		}
+		if item != nil && item.comment != nil {
+			hdr.PAXRecords = map[string]string{paxComment: *item.comment}
+		}
+		return tw.WriteHeader(hdr)
+	}
+
+	for _, item := range a.entries {
+		pathname := item.GetPathname()
+		if pathname == "" {
+			continue
+		}
+
+		// parent directories are written before their contents
+		for _, parent := range parentDirs(pathname) {
+			if err = writeDir(parent, nil); err != nil {
+				return
+			}
+		}
+
+		if item.IsDir() {
+			if err = writeDir(pathname, item); err != nil {
+				return
+			}
+			continue
+		}
+
+		body := item.GetBody()
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     pathname,
+			Size:     int64(len(body)),
+			Mode:     int64(options.fileMode().Perm()),
+			ModTime:  options.modTime(),
+		}
+		if item.comment != nil {
+			hdr.PAXRecords = map[string]string{paxComment: *item.comment}
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return
+		} else if _, err = io.WriteString(tw, body); err != nil {
+			return
+		}
+		a.report(pathname, OpExported, pathname)
+	}
+
+	err = tw.Close()
+	return
+}
+
+// FromTar creates a new Archive from the contents of the tar archive read from
+// the reader given. Only regular files and directories are supported, all
+// other types of tar members produce an ErrUnsupportedMember *Error. Files
+// must be plain utf-8 text and directories are only added as explicit
+// directory entries when they are empty or have a comment. Comments stored by
+// Archive.ToTar are restored. Members are validated the same as parsing,
+// duplicate pathnames and files used as parent directories produce an *Error
+// wrapping ErrDuplicatePath or ErrFileAsParentDir
+func FromTar(filename string, reader io.Reader) (a Archive, err error) {
+	hrx := newArchive(filename, "")
+	hrx.boundary = DefaultBoundary
+	tr := tar.NewReader(reader)
+
+	tracker := newPathTracker()
+	var items []*addedItem
+	for {
+		var hdr *tar.Header
+		if hdr, err = tr.Next(); errors.Is(err, io.EOF) {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+
+		switch hdr.Typeflag {
+
+		case tar.TypeXGlobalHeader:
+			if comment, ok := hdr.PAXRecords[paxComment]; ok {
+				if err = hrx.SetComment(comment); err != nil {
+					return
+				}
+			}
+			continue
+
+		case tar.TypeDir, tar.TypeReg:
+
+		default:
+			err = newError(hdr.Name, 0, ErrUnsupportedMember, ErrBadFileEntry)
+			return
+		}
+
+		item := &addedItem{
+			dir:     hdr.Typeflag == tar.TypeDir,
+			comment: hdr.PAXRecords[paxComment],
+			used:    true,
+		}
+		if item.pathname, err = memberPathname(hdr.Name, item.dir); err != nil {
+			return
+		} else if item.pathname == "" {
+			continue
+		} else if ee := tracker.add(item.pathname); ee != nil {
+			err = newError(hdr.Name, 0, ee, ErrMalformedInput)
+			return
+		}
+
+		if !item.dir {
+			var data []byte
+			if data, err = io.ReadAll(tr); err != nil {
+				return
+			} else if err = checkTextContent(hdr.Name, data); err != nil {
+				return
+			}
+			item.body = string(data)
+		}
+
+		items = append(items, item)
+	}
+
+	if err = hrx.addItems(items); err == nil {
+		a = hrx
+	}
+	return
+}
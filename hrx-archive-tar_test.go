@@ -0,0 +1,113 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/tdata"
+)
+
+func TestTar(t *testing.T) {
+	Convey("Tar Conversion", t, func() {
+
+		Convey("round-trip", func() {
+			a, err := ParseData("testing.hrx", tArchiveFsHRX)
+			So(err, ShouldBeNil)
+			So(a.SetComment("archive comment"), ShouldBeNil)
+			var buf bytes.Buffer
+			modTime := time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)
+			So(a.ToTar(&buf, &TarOptions{FileMode: 0600, ModTime: modTime}), ShouldBeNil)
+
+			var names []string
+			tr := tar.NewReader(bytes.NewReader(buf.Bytes()))
+			for hdr, ee := tr.Next(); ee == nil; hdr, ee = tr.Next() {
+				names = append(names, hdr.Name)
+				if hdr.Name == "file.txt" {
+					So(hdr.Mode, ShouldEqual, 0600)
+					So(hdr.ModTime.Equal(modTime), ShouldBeTrue)
+				}
+			}
+			So(names, ShouldEqual, []string{"pax_global_header", "file.txt", "dir/", "dir/file.txt", "dir/sub/", "dir/sub/deep.txt", "empty/", "one.hrx"})
+
+			b, err := FromTar("testing.hrx", bytes.NewReader(buf.Bytes()))
+			So(err, ShouldBeNil)
+			So(b.String(), ShouldEqual, a.String())
+		})
+
+		Convey("HRX Specification tar", func() {
+			a, err := FromTar("hrx-spec.hrx", strings.NewReader(tdata.New().F("hrx-spec.tar")))
+			So(err, ShouldBeNil)
+			body, _, ok := a.Get("simple.hrx")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, TD.F("simple.hrx"))
+			So(a.Entry("comment-only/.gitkeep"), ShouldNotBeNil)
+		})
+
+		Convey("unsupported members", func() {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			So(tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "target"}), ShouldBeNil)
+			So(tw.Close(), ShouldBeNil)
+			_, err := FromTar("testing.hrx", &buf)
+			So(errors.Is(err, ErrUnsupportedMember), ShouldBeTrue)
+
+			buf.Reset()
+			tw = tar.NewWriter(&buf)
+			So(tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "binary", Size: 3}), ShouldBeNil)
+			_, err = tw.Write([]byte{'a', 0, 'b'})
+			So(err, ShouldBeNil)
+			So(tw.Close(), ShouldBeNil)
+			_, err = FromTar("testing.hrx", &buf)
+			So(errors.Is(err, ErrBinaryContent), ShouldBeTrue)
+
+			buf.Reset()
+			tw = tar.NewWriter(&buf)
+			So(tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../escape"}), ShouldBeNil)
+			So(tw.Close(), ShouldBeNil)
+			_, err = FromTar("testing.hrx", &buf)
+			So(errors.Is(err, ErrContainsRelPath), ShouldBeTrue)
+		})
+
+		Convey("conflicting members", func() {
+			tarOf := func(names ...string) *bytes.Buffer {
+				var buf bytes.Buffer
+				tw := tar.NewWriter(&buf)
+				for _, name := range names {
+					So(tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name}), ShouldBeNil)
+				}
+				So(tw.Close(), ShouldBeNil)
+				return &buf
+			}
+
+			_, err := FromTar("testing.hrx", tarOf("a", "a/b", "a"))
+			So(errors.Is(err, ErrFileAsParentDir), ShouldBeTrue)
+			var e *Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.File, ShouldEqual, "a/b")
+
+			_, err = FromTar("testing.hrx", tarOf("a", "b", "a"))
+			So(errors.Is(err, ErrDuplicatePath), ShouldBeTrue)
+		})
+
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// txtarFile is a single file parsed from txtar data
+type txtarFile struct {
+	name string
+	line int
+	data string
+}
+
+func (a *archive) ToTxtar(w io.Writer) (err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var buf strings.Builder
+	if a.comment != nil {
+		if hasTxtarMarker(*a.comment) {
+			err = newError(a.filename, a.lastLine, ErrTxtarMarker, ErrMalformedInput)
+			return
+		}
+		buf.WriteString(withNewline(*a.comment))
+	}
+
+	for _, item := range a.entries {
+		pathname := item.GetPathname()
+		if item.comment != nil {
+			a.report(pathname, OpDropped, "comment")
+		}
+		if pathname == "" {
+			continue
+		} else if item.IsDir() {
+			a.report(pathname, OpDropped, "directory")
+			continue
+		}
+
+		body := item.GetBody()
+		if hasTxtarMarker(body) {
+			err = newError(a.filename, item.line, ErrTxtarMarker, ErrBadFileEntry)
+			return
+		}
+		buf.WriteString("-- " + pathname + " --\n")
+		buf.WriteString(withNewline(body))
+		a.report(pathname, OpExported, pathname)
+	}
+
+	_, err = io.WriteString(w, buf.String())
+	return
+}
+
+// FromTxtar creates a new Archive from the txtar data read from the reader
+// given. The leading txtar comment becomes the Archive comment and all files
+// are added in order with their bodies unmodified. FromTxtar returns an *Error
+// for any file that is not valid utf-8 text or which has a pathname not
+// allowed by the HRX specification, including an empty name
+func FromTxtar(filename string, reader io.Reader) (a Archive, err error) {
+	var data []byte
+	if data, err = io.ReadAll(reader); err != nil {
+		return
+	}
+
+	hrx := newArchive(filename, "")
+	hrx.boundary = DefaultBoundary
+	comment, files := parseTxtar(string(data))
+
+	if err = checkTextContent(filename, []byte(comment)); err != nil {
+		return
+	}
+
+	tracker := newPathTracker()
+	var items []*addedItem
+	for _, file := range files {
+		if ee := checkPathname(file.name); ee != nil || file.name == "" {
+			err = newError(filename, file.line, ee, ErrBadFileEntry)
+			return
+		} else if ee = tracker.add(file.name); ee != nil {
+			err = newError(filename, file.line, ee, ErrMalformedInput)
+			return
+		} else if err = checkTextContent(filename, []byte(file.data)); err != nil {
+			var e *Error
+			if errors.As(err, &e) {
+				// line numbers are relative to the file marker
+				e.Line += file.line
+			}
+			return
+		}
+		items = append(items, &addedItem{pathname: file.name, body: file.data, used: true})
+	}
+
+	if comment != "" {
+		if err = hrx.SetComment(comment); err != nil {
+			return
+		}
+	}
+
+	if err = hrx.addItems(items); err == nil {
+		a = hrx
+	}
+	return
+}
+
+// parseTxtar separates the leading comment from the files of the txtar data
+func parseTxtar(data string) (comment string, files []*txtarFile) {
+	var current *txtarFile
+	var buf strings.Builder
+	flush := func() {
+		if current == nil {
+			comment = buf.String()
+		} else {
+			current.data = buf.String()
+		}
+		buf.Reset()
+	}
+
+	for idx, line := range strings.SplitAfter(data, "\n") {
+		if name, ok := txtarMarker(line); ok {
+			flush()
+			current = &txtarFile{name: name, line: idx + 1}
+			files = append(files, current)
+			continue
+		}
+		buf.WriteString(line)
+	}
+
+	flush()
+	return
+}
+
+// txtarMarker returns the file name of the txtar "-- name --" marker line
+func txtarMarker(line string) (name string, ok bool) {
+	line = strings.TrimSuffix(line, "\n")
+	if ok = len(line) >= 6 && strings.HasPrefix(line, "-- ") && strings.HasSuffix(line, " --"); ok {
+		name = strings.TrimSpace(line[3 : len(line)-3])
+	}
+	return
+}
+
+// hasTxtarMarker returns true if any line of the text is a txtar file marker
+func hasTxtarMarker(text string) (found bool) {
+	for _, line := range strings.Split(text, "\n") {
+		if _, found = txtarMarker(line); found {
+			return
+		}
+	}
+	return
+}
+
+// withNewline returns the text with a trailing newline added if not empty and
+// not already present
+func withNewline(text string) string {
+	if text == "" || strings.HasSuffix(text, "\n") {
+		return text
+	}
+	return text + "\n"
+}
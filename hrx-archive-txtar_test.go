@@ -0,0 +1,112 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTxtar(t *testing.T) {
+	Convey("Txtar Conversion", t, func() {
+
+		Convey("HRX Specification round-trip", func() {
+			for _, hrxname := range gTestDataFiles {
+				expected, err := ParseData(hrxname, TD.F(hrxname))
+				if err != nil {
+					continue
+				}
+
+				dropped := make(map[string][]string)
+				expected.SetReporter(func(_, pathname, note string, argv ...interface{}) {
+					if note == OpDropped {
+						dropped[pathname] = append(dropped[pathname], argv[0].(string))
+					}
+				})
+
+				var buf strings.Builder
+				So(expected.ToTxtar(&buf), ShouldBeNil)
+				a, err := FromTxtar(hrxname, strings.NewReader(buf.String()))
+				So(err, ShouldBeNil)
+
+				var files []string
+				for _, pathname := range expected.List() {
+					if item := expected.Entry(pathname); item.IsDir() {
+						So(dropped[pathname], ShouldContain, "directory")
+						continue
+					} else if item.GetComment() != "" {
+						So(dropped[pathname], ShouldContain, "comment")
+					}
+					files = append(files, pathname)
+					expectedBody, _, _ := expected.Get(pathname)
+					actualBody, _, _ := a.Get(pathname)
+					So(actualBody, ShouldEqual, withNewline(expectedBody))
+				}
+				So(a.List(), ShouldEqual, files)
+
+				expectedComment, _ := expected.GetComment()
+				actualComment, _ := a.GetComment()
+				So(actualComment, ShouldEqual, withNewline(expectedComment))
+
+				// txtar output is stable once converted
+				var again strings.Builder
+				So(a.ToTxtar(&again), ShouldBeNil)
+				So(again.String(), ShouldEqual, buf.String())
+			}
+		})
+
+		Convey("txtar round-trip", func() {
+			contents := "leading comment\n-- one.txt --\nfirst\n-- dir/two.txt --\nsecond\n\n-- empty --\n-- last.txt --\nno newline"
+			a, err := FromTxtar("testing.txtar", strings.NewReader(contents))
+			So(err, ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"one.txt", "dir/two.txt", "empty", "last.txt"})
+			comment, ok := a.GetComment()
+			So(ok, ShouldBeTrue)
+			So(comment, ShouldEqual, "leading comment\n")
+			body, _, _ := a.Get("dir/two.txt")
+			So(body, ShouldEqual, "second\n\n")
+			body, _, _ = a.Get("last.txt")
+			So(body, ShouldEqual, "no newline")
+			var buf strings.Builder
+			So(a.ToTxtar(&buf), ShouldBeNil)
+			So(buf.String(), ShouldEqual, contents+"\n")
+		})
+
+		Convey("errors", func() {
+			_, err := FromTxtar("testing.txtar", strings.NewReader("-- file --\none\n-- file --\ntwo\n"))
+			So(errors.Is(err, ErrDuplicatePath), ShouldBeTrue)
+			_, err = FromTxtar("testing.txtar", strings.NewReader("-- ../file --\n"))
+			So(errors.Is(err, ErrContainsRelPath), ShouldBeTrue)
+			_, err = FromTxtar("testing.txtar", strings.NewReader("--  --\nfoo\n-- f --\nbar\n"))
+			So(errors.Is(err, ErrBadFileEntry), ShouldBeTrue)
+			var empty *Error
+			So(errors.As(err, &empty), ShouldBeTrue)
+			So(empty.Line, ShouldEqual, 1)
+			_, err = FromTxtar("testing.txtar", strings.NewReader("-- file --\none\n\xff\n"))
+			So(errors.Is(err, ErrInvalidUnicode), ShouldBeTrue)
+			var e *Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.Line, ShouldEqual, 3)
+
+			a := New("testing.hrx", "")
+			So(a.Set("file", "-- not a file --\n", ""), ShouldBeNil)
+			So(errors.Is(a.ToTxtar(&strings.Builder{}), ErrTxtarMarker), ShouldBeTrue)
+		})
+
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DriftKind describes how a path on disk differs from an archive
+type DriftKind string
+
+const (
+	// DriftMissing indicates an archive file or directory does not exist
+	DriftMissing DriftKind = "missing"
+	// DriftExtra indicates a file or directory exists which is not within
+	// the archive
+	DriftExtra DriftKind = "extra"
+	// DriftModified indicates a file exists with contents which differ from
+	// the archive, or is not the same type of path as the archive entry
+	DriftModified DriftKind = "modified"
+)
+
+// VerifyOptions configures the behaviour of Archive.Verify
+type VerifyOptions struct {
+	// IgnoreExtra skips reporting files and directories which are not within
+	// the archive
+	IgnoreExtra bool
+}
+
+func (o *VerifyOptions) ignoreExtra() bool {
+	return o != nil && o.IgnoreExtra
+}
+
+// Drift is a single difference between an archive and a directory on disk
+type Drift struct {
+	// Pathname is the archive pathname, directories end with a slash
+	Pathname string
+	// Kind is how this pathname differs
+	Kind DriftKind
+}
+
+func (a *archive) Verify(destination string, options *VerifyOptions) (drift []*Drift, err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var info os.FileInfo
+	if info, err = os.Stat(destination); err != nil {
+		return
+	} else if !info.IsDir() {
+		err = ErrDstIsFile
+		return
+	}
+
+	// all expected pathnames, with directories ending in a slash
+	expected := make(map[string]*entry)
+	for _, item := range a.entries {
+		pathname := item.GetPathname()
+		if pathname == "" {
+			continue
+		}
+		for _, parent := range parentDirs(pathname) {
+			if _, present := expected[parent]; !present {
+				expected[parent] = nil
+			}
+		}
+		expected[pathname] = item
+	}
+
+	seen := make(map[string]struct{})
+	err = fs.WalkDir(os.DirFS(destination), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if name == "." {
+			return nil
+		}
+
+		pathname := name
+		if d.IsDir() {
+			pathname += "/"
+		}
+
+		item, present := expected[pathname]
+		if !present {
+			// check for an entry of a different type
+			other := strings.TrimSuffix(pathname, "/")
+			if other == pathname {
+				other += "/"
+			}
+			if _, found := expected[other]; found {
+				seen[other] = struct{}{}
+				drift = append(drift, &Drift{Pathname: other, Kind: DriftModified})
+			} else if !options.ignoreExtra() {
+				drift = append(drift, &Drift{Pathname: pathname, Kind: DriftExtra})
+			}
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		seen[pathname] = struct{}{}
+		if d.IsDir() {
+			return nil
+		} else if !d.Type().IsRegular() {
+			drift = append(drift, &Drift{Pathname: pathname, Kind: DriftModified})
+			return nil
+		}
+
+		data, ee := os.ReadFile(filepath.Join(destination, filepath.FromSlash(name)))
+		if ee != nil {
+			return ee
+		} else if string(data) != item.GetBody() {
+			drift = append(drift, &Drift{Pathname: pathname, Kind: DriftModified})
+		}
+		return nil
+	})
+	if err != nil {
+		drift = nil
+		return
+	}
+
+	for pathname := range expected {
+		if _, present := seen[pathname]; !present {
+			drift = append(drift, &Drift{Pathname: pathname, Kind: DriftMissing})
+		}
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].Pathname < drift[j].Pathname
+	})
+	return
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/tdata"
+)
+
+func TestVerify(t *testing.T) {
+	Convey("Verify", t, func() {
+		tempdir, err := tdata.NewTempData("", "hrx-lib.Verify.*")
+		So(err, ShouldBeNil)
+		defer tempdir.Destroy()
+
+		a, err := ParseData("testing.hrx", tArchiveFsHRX)
+		So(err, ShouldBeNil)
+		So(a.ExtractTo(tempdir.Path()), ShouldBeNil)
+
+		Convey("no drift after extraction", func() {
+			drift, ee := a.Verify(tempdir.Path(), nil)
+			So(ee, ShouldBeNil)
+			So(drift, ShouldBeEmpty)
+		})
+
+		Convey("missing, extra and modified", func() {
+			So(os.WriteFile(tempdir.Join("file.txt"), []byte("changed\n"), 0640), ShouldBeNil)
+			So(os.RemoveAll(tempdir.Join("dir", "sub")), ShouldBeNil)
+			So(os.Remove(tempdir.Join("empty")), ShouldBeNil)
+			So(os.WriteFile(tempdir.Join("empty"), []byte{}, 0640), ShouldBeNil)
+			So(os.WriteFile(tempdir.Join("extra.txt"), []byte{0, 1, 2}, 0640), ShouldBeNil)
+			So(os.MkdirAll(tempdir.Join("extra", "nested"), 0770), ShouldBeNil)
+
+			drift, ee := a.Verify(tempdir.Path(), nil)
+			So(ee, ShouldBeNil)
+			So(drift, ShouldEqual, []*Drift{
+				{Pathname: "dir/sub/", Kind: DriftMissing},
+				{Pathname: "dir/sub/deep.txt", Kind: DriftMissing},
+				{Pathname: "empty/", Kind: DriftModified},
+				{Pathname: "extra.txt", Kind: DriftExtra},
+				{Pathname: "extra/", Kind: DriftExtra},
+				{Pathname: "file.txt", Kind: DriftModified},
+			})
+
+			drift, ee = a.Verify(tempdir.Path(), &VerifyOptions{IgnoreExtra: true})
+			So(ee, ShouldBeNil)
+			So(drift, ShouldHaveLength, 4)
+		})
+
+		Convey("destination errors", func() {
+			_, ee := a.Verify(tempdir.Join("file.txt"), nil)
+			So(errors.Is(ee, ErrDstIsFile), ShouldBeTrue)
+			_, ee = a.Verify(tempdir.Join("not-found"), nil)
+			So(errors.Is(ee, fs.ErrNotExist), ShouldBeTrue)
+		})
+
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+// WalkFunc is the type of the function called by Archive.Walk for each file
+// and directory visited, see fs.WalkDirFunc for the details of how the
+// arguments and return values are used
+type WalkFunc func(pathname string, d fs.DirEntry, err error) error
+
+// WalkOptions configures the behaviour of Archive.Walk
+type WalkOptions struct {
+	// Nested presents entries where IsHRX is true as directories and walks
+	// their contents, otherwise they are walked as regular files
+	Nested bool
+}
+
+func (o *WalkOptions) nested() bool {
+	return o != nil && o.Nested
+}
+
+// IsNestedArchive reports whether the fs.DirEntry given to a WalkFunc is a
+// nested archive presented as a directory
+func IsNestedArchive(d fs.DirEntry) (nested bool) {
+	if info, ok := d.(*fsFileInfo); ok {
+		nested = info.nested
+	}
+	return
+}
+
+func (a *archive) Walk(root string, fn WalkFunc, options *WalkOptions) (err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if err = a.walkRoot(root, "", options.nested(), fn); errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		err = nil
+	}
+	return
+}
+
+// walkRoot resolves the root given, which may be a nested address, and walks
+// the file tree from there
+func (a *archive) walkRoot(root, prefix string, nested bool, fn WalkFunc) (err error) {
+	if outer, inner, ok := splitNestedPath(root); ok {
+		var ia *archive
+		if ia, err = a.getNested(outer); err != nil {
+			return fn(prefix+root, nil, &fs.PathError{Op: "walk", Path: prefix + root, Err: err})
+		}
+		return ia.walkRoot(inner, prefix+outer+NestedSeparator, nested, fn)
+	}
+
+	var info *fsFileInfo
+	if info, err = a.fsStat("walk", root); err != nil {
+		return fn(prefix+root, nil, err)
+	}
+	return a.walk(root, prefix, walkInfo(info, nested), nested, fn)
+}
+
+// walk is modeled on the internals of fs.WalkDir, with nested archives
+// walked as directories when nested is true
+func (a *archive) walk(name, prefix string, d *fsFileInfo, nested bool, fn WalkFunc) (err error) {
+	pathname := prefix + name
+	if err = fn(pathname, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return
+	}
+
+	dir, children := a, []fs.DirEntry(nil)
+	if d.nested {
+		var ee error
+		if dir, ee = d.entry.parseNested(); ee != nil {
+			if err = fn(pathname, d, ee); errors.Is(err, fs.SkipDir) {
+				err = nil
+			}
+			return
+		}
+		name, prefix = ".", pathname+NestedSeparator
+	}
+	children = dir.fsReadDir(name)
+
+	for _, child := range children {
+		if err = dir.walk(path.Join(name, child.Name()), prefix, walkInfo(child.(*fsFileInfo), nested), nested, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				err = nil
+				break
+			}
+			return
+		}
+	}
+	return
+}
+
+// walkInfo presents nested archive files as directories when nested is true
+func walkInfo(info *fsFileInfo, nested bool) (walked *fsFileInfo) {
+	if nested && info.entry != nil && info.entry.IsHRX() {
+		return &fsFileInfo{
+			name:   info.name,
+			mode:   fs.ModeDir | clPath.DefaultPathPerms,
+			entry:  info.entry,
+			nested: true,
+		}
+	}
+	return info
+}
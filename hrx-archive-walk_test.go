@@ -0,0 +1,149 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"io/fs"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tWalkHRX = `<===> top.txt
+top
+<===> dir/sub/file.txt
+file
+<===> dir/empty/
+<===> dir/nested.hrx
+<====> inner/one.txt
+one
+<====> two.txt
+two
+<===> broken.hrx
+not an archive
+<===> last.txt
+last
+`
+
+func TestWalk(t *testing.T) {
+	Convey("Walk", t, func() {
+		a, err := ParseData("walk.hrx", tWalkHRX)
+		So(err, ShouldBeNil)
+
+		options := &WalkOptions{Nested: true}
+		walk := func(root string, fn func(pathname string, d fs.DirEntry) error) (visited []string, err error) {
+			err = a.Walk(root, func(pathname string, d fs.DirEntry, err error) error {
+				if err != nil {
+					visited = append(visited, "error:"+pathname)
+					return nil
+				}
+				if IsNestedArchive(d) {
+					visited = append(visited, pathname+"//")
+				} else if d.IsDir() && pathname != "." {
+					visited = append(visited, pathname+"/")
+				} else {
+					visited = append(visited, pathname)
+				}
+				if fn != nil {
+					return fn(pathname, d)
+				}
+				return nil
+			}, options)
+			return
+		}
+
+		Convey("tree order with nested archives", func() {
+			visited, err := walk(".", nil)
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{
+				".",
+				"broken.hrx//",
+				"error:broken.hrx",
+				"dir/",
+				"dir/empty/",
+				"dir/nested.hrx//",
+				"dir/nested.hrx//inner/",
+				"dir/nested.hrx//inner/one.txt",
+				"dir/nested.hrx//two.txt",
+				"dir/sub/",
+				"dir/sub/file.txt",
+				"last.txt",
+				"top.txt",
+			})
+		})
+
+		Convey("SkipDir and SkipAll", func() {
+			visited, err := walk(".", func(pathname string, d fs.DirEntry) error {
+				if IsNestedArchive(d) || pathname == "dir/empty" {
+					return fs.SkipDir
+				} else if pathname == "dir/sub/file.txt" {
+					return fs.SkipAll
+				}
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{".", "broken.hrx//", "dir/", "dir/empty/", "dir/nested.hrx//", "dir/sub/", "dir/sub/file.txt"})
+
+			visited, err = walk(".", func(pathname string, d fs.DirEntry) error {
+				if pathname == "dir/empty" {
+					return fs.SkipDir
+				}
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(visited[len(visited)-1], ShouldEqual, "top.txt")
+		})
+
+		Convey("nested archives as files", func() {
+			options = nil
+			visited, err := walk(".", nil)
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{
+				".",
+				"broken.hrx",
+				"dir/",
+				"dir/empty/",
+				"dir/nested.hrx",
+				"dir/sub/",
+				"dir/sub/file.txt",
+				"last.txt",
+				"top.txt",
+			})
+
+			visited, err = walk("dir/nested.hrx", nil)
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{"dir/nested.hrx"})
+
+			// nested roots are always resolved
+			visited, err = walk("dir/nested.hrx//inner", nil)
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{"dir/nested.hrx//inner/", "dir/nested.hrx//inner/one.txt"})
+		})
+
+		Convey("nested roots", func() {
+			visited, err := walk("dir/nested.hrx", nil)
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{"dir/nested.hrx//", "dir/nested.hrx//inner/", "dir/nested.hrx//inner/one.txt", "dir/nested.hrx//two.txt"})
+
+			visited, err = walk("dir/nested.hrx//inner", nil)
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{"dir/nested.hrx//inner/", "dir/nested.hrx//inner/one.txt"})
+
+			visited, err = walk("missing", nil)
+			So(err, ShouldBeNil)
+			So(visited, ShouldEqual, []string{"error:missing"})
+		})
+	})
+}
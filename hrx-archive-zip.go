@@ -0,0 +1,209 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"time"
+
+	clPath "github.com/go-corelibs/path"
+)
+
+// ZipOptions configures the zip headers produced by Archive.ToZip
+type ZipOptions struct {
+	// FileMode is the permissions of files, defaults to DefaultFileMode
+	FileMode os.FileMode
+	// DirMode is the permissions of directories, defaults to
+	// path.DefaultPathPerms
+	DirMode os.FileMode
+	// ModTime is the modification time of all entries, defaults to the
+	// MS-DOS epoch of 1980-01-01
+	ModTime time.Time
+	// Store disables compression of file contents
+	Store bool
+}
+
+func (o *ZipOptions) fileMode() os.FileMode {
+	if o == nil || o.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return o.FileMode
+}
+
+func (o *ZipOptions) dirMode() os.FileMode {
+	if o == nil || o.DirMode == 0 {
+		return clPath.DefaultPathPerms
+	}
+	return o.DirMode
+}
+
+func (o *ZipOptions) modTime() time.Time {
+	if o == nil || o.ModTime.IsZero() {
+		return time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return o.ModTime
+}
+
+func (o *ZipOptions) method() uint16 {
+	if o != nil && o.Store {
+		return zip.Store
+	}
+	return zip.Deflate
+}
+
+func (a *archive) ToZip(w io.Writer, options *ZipOptions) (err error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	zw := zip.NewWriter(w)
+	if a.comment != nil {
+		if err = zw.SetComment(*a.comment); err != nil {
+			return
+		}
+	}
+
+	written := make(map[string]struct{})
+	writeDir := func(pathname string, item *entry) (err error) {
+		if _, present := written[pathname]; present {
+			return
+		}
+		written[pathname] = struct{}{}
+		hdr := &zip.FileHeader{
+			Name:     pathname,
+			Method:   zip.Store,
+			Modified: options.modTime(),
+		}
+		hdr.SetMode(options.dirMode().Perm() | os.ModeDir)
+		if item != nil {
+			hdr.Comment = item.GetComment()
+		}
+		if _, err = zw.CreateHeader(hdr); err == nil {
+			a.report(pathname, OpExported, pathname)
+		}
+		return
+	}
+
+	for _, item := range a.entries {
+		pathname := item.GetPathname()
+		if pathname == "" {
+			continue
+		}
+
+		// parent directories are written before their contents
+		for _, parent := range parentDirs(pathname) {
+			if err = writeDir(parent, nil); err != nil {
+				return
+			}
+		}
+
+		if item.IsDir() {
+			if err = writeDir(pathname, item); err != nil {
+				return
+			}
+			continue
+		}
+
+		hdr := &zip.FileHeader{
+			Name:     pathname,
+			Comment:  item.GetComment(),
+			Method:   options.method(),
+			Modified: options.modTime(),
+		}
+		hdr.SetMode(options.fileMode().Perm())
+		var fw io.Writer
+		if fw, err = zw.CreateHeader(hdr); err != nil {
+			return
+		} else if _, err = io.WriteString(fw, item.GetBody()); err != nil {
+			return
+		}
+		a.report(pathname, OpExported, pathname)
+	}
+
+	err = zw.Close()
+	return
+}
+
+// FromZip creates a new Archive from the contents of the zip archive given.
+// Only regular files and directories are supported, all other types of zip
+// members produce an ErrUnsupportedMember *Error. Files must be plain utf-8
+// text and directories are only added as explicit directory entries when they
+// are empty or have a comment. The zip archive comment becomes the Archive
+// comment and per-file comments become entry comments. Members are validated
+// the same as parsing, duplicate pathnames and files used as parent
+// directories produce an *Error wrapping ErrDuplicatePath or
+// ErrFileAsParentDir
+func FromZip(filename string, reader *zip.Reader) (a Archive, err error) {
+	hrx := newArchive(filename, "")
+	hrx.boundary = DefaultBoundary
+
+	tracker := newPathTracker()
+	var items []*addedItem
+	for _, file := range reader.File {
+		mode := file.Mode()
+		if !mode.IsDir() && !mode.IsRegular() {
+			err = newError(file.Name, 0, ErrUnsupportedMember, ErrBadFileEntry)
+			return
+		}
+
+		item := &addedItem{
+			dir:     mode.IsDir(),
+			comment: file.Comment,
+			used:    true,
+		}
+		if item.pathname, err = memberPathname(file.Name, item.dir); err != nil {
+			return
+		} else if item.pathname == "" {
+			continue
+		} else if ee := tracker.add(item.pathname); ee != nil {
+			err = newError(file.Name, 0, ee, ErrMalformedInput)
+			return
+		}
+
+		if !item.dir {
+			var data []byte
+			if data, err = readZipFile(file); err != nil {
+				return
+			} else if err = checkTextContent(file.Name, data); err != nil {
+				return
+			}
+			item.body = string(data)
+		}
+
+		items = append(items, item)
+	}
+
+	if reader.Comment != "" {
+		if err = hrx.SetComment(reader.Comment); err != nil {
+			return
+		}
+	}
+
+	if err = hrx.addItems(items); err == nil {
+		a = hrx
+	}
+	return
+}
+
+func readZipFile(file *zip.File) (data []byte, err error) {
+	var rc io.ReadCloser
+	if rc, err = file.Open(); err != nil {
+		return
+	}
+	defer rc.Close()
+	data, err = io.ReadAll(rc)
+	return
+}
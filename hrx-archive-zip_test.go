@@ -0,0 +1,113 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestZip(t *testing.T) {
+	Convey("Zip Conversion", t, func() {
+
+		Convey("round-trip", func() {
+			a, err := ParseData("testing.hrx", tArchiveFsHRX)
+			So(err, ShouldBeNil)
+			So(a.SetComment("archive comment"), ShouldBeNil)
+			So(a.Set("dir/file.txt", "nested file contents\n", "file comment"), ShouldBeNil)
+			var buf bytes.Buffer
+			So(a.ToZip(&buf, &ZipOptions{FileMode: 0600}), ShouldBeNil)
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			So(err, ShouldBeNil)
+			So(zr.Comment, ShouldEqual, "archive comment")
+			var names []string
+			for _, file := range zr.File {
+				names = append(names, file.Name)
+				if file.Name == "dir/file.txt" {
+					So(file.Comment, ShouldEqual, "file comment")
+					So(file.Mode(), ShouldEqual, os.FileMode(0600))
+				}
+			}
+			So(names, ShouldEqual, []string{"file.txt", "dir/", "dir/file.txt", "dir/sub/", "dir/sub/deep.txt", "empty/", "one.hrx"})
+
+			b, err := FromZip("testing.hrx", zr)
+			So(err, ShouldBeNil)
+			So(b.String(), ShouldEqual, a.String())
+		})
+
+		Convey("unsupported members", func() {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			hdr := &zip.FileHeader{Name: "link"}
+			hdr.SetMode(os.ModeSymlink | 0777)
+			_, err := zw.CreateHeader(hdr)
+			So(err, ShouldBeNil)
+			So(zw.Close(), ShouldBeNil)
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			So(err, ShouldBeNil)
+			_, err = FromZip("testing.hrx", zr)
+			So(errors.Is(err, ErrUnsupportedMember), ShouldBeTrue)
+
+			buf.Reset()
+			zw = zip.NewWriter(&buf)
+			fw, err := zw.Create("invalid.txt")
+			So(err, ShouldBeNil)
+			_, err = fw.Write([]byte("line one\n\xff\n"))
+			So(err, ShouldBeNil)
+			So(zw.Close(), ShouldBeNil)
+			zr, err = zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			So(err, ShouldBeNil)
+			_, err = FromZip("testing.hrx", zr)
+			So(errors.Is(err, ErrInvalidUnicode), ShouldBeTrue)
+			var e *Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.Line, ShouldEqual, 2)
+		})
+
+		Convey("conflicting members", func() {
+			zipOf := func(names ...string) *zip.Reader {
+				var buf bytes.Buffer
+				zw := zip.NewWriter(&buf)
+				for _, name := range names {
+					_, err := zw.Create(name)
+					So(err, ShouldBeNil)
+				}
+				So(zw.Close(), ShouldBeNil)
+				zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+				So(err, ShouldBeNil)
+				return zr
+			}
+
+			_, err := FromZip("testing.hrx", zipOf("a", "a/b"))
+			So(errors.Is(err, ErrFileAsParentDir), ShouldBeTrue)
+			var e *Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.File, ShouldEqual, "a/b")
+
+			_, err = FromZip("testing.hrx", zipOf("a/b", "a"))
+			So(errors.Is(err, ErrFileAsParentDir), ShouldBeTrue)
+
+			_, err = FromZip("testing.hrx", zipOf("a/", "a/b", "a/"))
+			So(errors.Is(err, ErrDuplicatePath), ShouldBeTrue)
+		})
+
+	})
+}
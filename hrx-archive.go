@@ -15,6 +15,8 @@
 package hrx
 
 import (
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sync"
@@ -53,6 +55,21 @@ const (
 	// OpUpdated is the ReporterFn note used when a file is updated within
 	// an Archive instance during Archive.Set
 	OpUpdated = "updated"
+	// OpRenamed is the ReporterFn note used when an entry is renamed during
+	// Archive.Rename or Archive.MoveDir. The argument is the new pathname
+	OpRenamed = "renamed"
+	// OpMoved is the ReporterFn note used when an entry changes position
+	// during Archive.InsertBefore, Archive.InsertAfter, Archive.Move or
+	// Archive.Sort. The argument is the new index of the entry
+	OpMoved = "moved"
+	// OpExported is the ReporterFn note used when an entry is written to
+	// another archive format, such as during Archive.ToTar or Archive.ToZip
+	OpExported = "exported"
+	// OpDropped is the ReporterFn note used when an entry, or part of an
+	// entry, cannot be represented in another archive format, such as
+	// directories and comments during Archive.ToTxtar. The argument is a
+	// description of what was dropped
+	OpDropped = "dropped"
 	// OpBoundary is the ReporterFn note used when the Archive boundary is
 	// changed. Note that all nested archives also emit this report when
 	// the top-level Archive.SetBoundary call is made. The top-level report
@@ -73,8 +90,21 @@ type Archive interface {
 	// within the HRX headers)
 	GetBoundary() (size int)
 
-	// SetComment specifies a general comment for this archive
-	SetComment(comment string)
+	// MinimalBoundary returns the smallest boundary size which can be used
+	// with SetBoundary without any body or comment lines, including those
+	// within nested archives, being mistaken for boundary lines
+	MinimalBoundary() (size int)
+
+	// Normalize is a convenience wrapper around SetBoundary with the size
+	// returned by MinimalBoundary
+	Normalize() (err error)
+
+	// SetComment specifies a general comment for this archive. If the
+	// comment contains lines which would be mistaken for boundary lines, the
+	// boundary of this archive is raised (see MinimalBoundary) and SetComment
+	// may return an error from SetBoundary, in which case the previous
+	// comment is kept
+	SetComment(comment string) (err error)
 
 	// GetComment returns the general comment for this archive, if one exists
 	GetComment() (comment string, ok bool)
@@ -83,19 +113,33 @@ type Archive interface {
 	DeleteComment()
 
 	// Set adds or overwrites pathname with the given body and comment. Empty
-	// comments are ignored. Set may return an error if another HRX file is
-	// being set and a parsing error happened while adjusting the nested
-	// archive's boundary size
+	// comments are ignored. Set may return an error if the body or comment
+	// contain invalid unicode
+	//
+	// If the body or comment contain lines which would be mistaken for
+	// boundary lines, the boundary of this archive is raised to the smallest
+	// size greater than the current boundary which is safe to use (see
+	// MinimalBoundary). Set returns any error from SetBoundary, in which case
+	// the archive is left unchanged
+	//
+	// The pathname may be a nested address (see NestedPath), in which case
+	// the nested archive is updated and each enclosing .hrx entry is
+	// re-serialized, creating any missing nested archives along the way. An
+	// error is returned if any of the enclosing archives fail to parse
 	Set(pathname, body, comment string) (err error)
 
-	// Get returns the body and any comment for the given pathname
+	// Get returns the body and any comment for the given pathname, which may
+	// be a nested address (see NestedPath)
 	Get(pathname string) (body, comment string, ok bool)
 
-	// Delete removes the pathname entry
+	// Delete removes the pathname entry. The pathname may be a nested
+	// address (see NestedPath), in which case each enclosing .hrx entry is
+	// re-serialized
 	Delete(pathname string)
 
-	// Entry returns a read-only interface for a specific pathname. Returns
-	// nil if there is no entry for the specified pathname
+	// Entry returns a read-only interface for a specific pathname, which may
+	// be a nested address (see NestedPath). Returns nil if there is no entry
+	// for the specified pathname
 	Entry(pathname string) Entry
 
 	// Len returns the number of entries stored within this archive. Len does
@@ -107,19 +151,74 @@ type Archive interface {
 	// within this archive. List does not recurse into nested HRX files
 	List() (pathnames []string)
 
+	// Select returns the pathnames of all entries selected by the fn given, in
+	// the order they are found within this archive. Use Glob to select entries
+	// with glob patterns. Select does not recurse into nested HRX files
+	Select(fn SelectFn) (pathnames []string)
+
+	// Filter returns a new Archive instance with copies of the entries which
+	// are selected by the fn given, along with the boundary and comment of
+	// this archive. Filter is useful for exporting a subset of entries, for
+	// example: a.Filter(Glob("testdata/**")).ToTar(w, nil)
+	Filter(fn SelectFn) (filtered Archive)
+
+	// DeleteSelected removes all entries selected by the fn given and returns
+	// the pathnames deleted
+	DeleteSelected(fn SelectFn) (pathnames []string)
+
 	// Entries returns a list of all file and directory entries within this
 	// Archive instance. Entries does not recurse into embedded HRX Archive
 	// files
 	Entries() (entries []Entry)
 
+	// Rename changes the pathname of an entry, keeping its position and
+	// comment. Rename returns an *Error with ErrDuplicatePath or
+	// ErrFileAsParentDir when the new pathname collides with another entry.
+	// Renaming an entry into or out of a nested archive is supported by
+	// moving the entry, which appends it to the destination archive. The new
+	// pathname is validated at each level of nesting
+	Rename(oldPath, newPath string) (err error)
+
+	// MoveDir renames all entries within the oldPrefix directory, including
+	// the explicit directory entry, to be within the newPrefix directory.
+	// Both prefixes must be within the same archive level, see Rename for
+	// the collision semantics
+	MoveDir(oldPrefix, newPrefix string) (err error)
+
+	// InsertBefore adds a new entry, positioned immediately before the
+	// existing pathname entry. The new pathname must be within the same
+	// archive level and not already present, see Set for the other details
+	InsertBefore(pathname, newPath, body, comment string) (err error)
+
+	// InsertAfter is like InsertBefore, positioning the new entry
+	// immediately after the existing pathname entry
+	InsertAfter(pathname, newPath, body, comment string) (err error)
+
+	// Move repositions the pathname entry to the index given, where the
+	// index is the position within the List of the same archive level
+	Move(pathname string, index int) (err error)
+
+	// Sort reorders the entries of this archive using the CompareFn given,
+	// keeping entries which compare equal in their current order. A nil fn
+	// uses LexicalOrder. Comments remain attached to their entries and
+	// nested archives are not sorted
+	Sort(fn CompareFn)
+
 	// ParseHRX looks for the entry associated with the given pathname and if
 	// the pathname has the `.hrx` extension, attempts to parse the contents
-	// into a new Archive instance
+	// into a new Archive instance. The pathname may be a nested address (see
+	// NestedPath)
 	ParseHRX(pathname string) (parsed Archive, err error)
 
-	// String returns the actual contents of this archive
+	// String returns the actual contents of this archive. Parsed archives
+	// are reproduced byte-for-byte, edits only change the bytes of the
+	// entries affected (unless the boundary is changed)
 	String() (archive string)
 
+	// Syntax returns the concrete syntax of this archive, the Text of each
+	// SyntaxNode concatenated is exactly the String output
+	Syntax() (nodes []SyntaxNode)
+
 	// WriteFile takes the String of this Archive and writes the contents to
 	// the local filesystem, at the path given. WriteFile will attempt to
 	// make all parent directories for the destination file
@@ -128,13 +227,82 @@ type Archive interface {
 	// ExtractTo extracts all of this Archive's entries to their individual
 	// files on the local filesystem. If any pathnames are also given then
 	// only those will be extracted. If no pathnames are given, all files are
-	// extracted
+	// extracted. The pathnames may be glob patterns, see MatchGlob
 	ExtractTo(destination string, pathnames ...string) (err error)
 
+	// ExtractSelected is like ExtractTo, extracting only the entries selected
+	// by the fn given
+	ExtractSelected(destination string, fn SelectFn) (err error)
+
+	// ExtractWith is like ExtractTo, extracting the entries selected by the
+	// options given with their pathnames remapped, see ExtractOptions. The
+	// ReporterFn is given the original pathname and the final filesystem path
+	ExtractWith(destination string, options *ExtractOptions) (err error)
+
+	// Verify compares this Archive's entries with the files and directories
+	// found within the destination directory, returning the Drift of all
+	// missing, extra and modified pathnames in lexical order. Implied parent
+	// directories are expected to exist along with explicit directory entries
+	Verify(destination string, options *VerifyOptions) (drift []*Drift, err error)
+
+	// AddFS walks the fsys given, starting at root, and adds all regular files
+	// found with pathnames relative to the root. Files are added in lexical
+	// order and empty directories are added as explicit directory entries.
+	// AddFS returns an *Error for any file that is not valid utf-8 text or
+	// which has a pathname not allowed by the HRX specification
+	AddFS(fsys fs.FS, root string, options *AddOptions) (err error)
+
+	// ToTar writes all entries to the writer given as a tar archive. Implied
+	// parent directories are included as explicit directory members and any
+	// comments are stored as PAX "comment" records, the archive comment
+	// within a PAX global header
+	ToTar(w io.Writer, options *TarOptions) (err error)
+
+	// ToZip writes all entries to the writer given as a zip archive. Implied
+	// parent directories are included as explicit directory members, entry
+	// comments are stored as zip file comments and the archive comment as the
+	// zip archive comment
+	ToZip(w io.Writer, options *ZipOptions) (err error)
+
+	// ToTxtar writes all files to the writer given in the txtar format used
+	// by Go test fixtures. The archive comment becomes the leading txtar
+	// comment. Txtar has no support for directories or per-file comments,
+	// these are dropped and reported with OpDropped. File bodies which do not
+	// end with a newline have one added, as txtar requires
+	ToTxtar(w io.Writer) (err error)
+
 	// SetReporter configures the internal event reporter function. This is
 	// only really useful for user-interfaces requiring notifications whenever
 	// an operation is performed
 	SetReporter(fn ReporterFn)
+
+	// Open implements fs.FS. Parent directories that are not explicitly
+	// present within this archive are synthesized from the pathnames of
+	// their contents. Nested HRX files are presented as regular files
+	Open(name string) (file fs.File, err error)
+
+	// ReadDir implements fs.ReadDirFS, returning the sorted list of entries
+	// within the named directory, including any implied directories
+	ReadDir(name string) (entries []fs.DirEntry, err error)
+
+	// Stat implements fs.StatFS. The fs.FileInfo.Sys method returns the
+	// read-only Entry for files and explicit directories
+	Stat(name string) (info fs.FileInfo, err error)
+
+	// Walk walks the file tree rooted at root, calling fn for each file and
+	// directory in the same manner as fs.WalkDir, including implied
+	// directories and the root itself. When options.Nested is true, nested
+	// archives are presented as directories (see IsNestedArchive) and are
+	// descended into, unless fn returns fs.SkipDir for them. The pathnames
+	// given to fn within nested archives are nested addresses (see
+	// NestedPath) and the root may also be a nested address, regardless of
+	// options.Nested
+	Walk(root string, fn WalkFunc, options *WalkOptions) (err error)
+
+	// Sub implements fs.SubFS, returning a new Archive instance containing
+	// copies of all entries found within the named directory, with the
+	// directory prefix removed from each pathname
+	Sub(dir string) (sub fs.FS, err error)
 }
 
 type archive struct {
@@ -145,9 +313,16 @@ type archive struct {
 	lookup   map[string]*entry
 	comment  *string
 	lastLine int
+	// commentHeader is the boundary line of the archive comment as parsed,
+	// see boundaryLine
+	commentHeader string
 
 	rfn ReporterFn
 
+	// lenient and errs are only used while parsing, see ParseOptions
+	lenient bool
+	errs    []error
+
 	mutex *sync.RWMutex
 }
 
@@ -164,10 +339,26 @@ func newArchive(filename, comment string) *archive {
 	return a
 }
 
-func (a *archive) error(line, offset int, wrap, base error) (err error) {
+func (a *archive) error(line int, wrap, base error) (err error) {
 	return newError(a.filename, line, wrap, base)
 }
 
+// fail records the error given and returns nil when parsing leniently,
+// otherwise the error is returned to stop parsing
+func (a *archive) fail(err error) error {
+	if a.lenient {
+		a.errs = append(a.errs, err)
+		return nil
+	}
+	return err
+}
+
+// errorAt is like error, positioned at the column of the item header as
+// parsed, when known
+func (a *archive) errorAt(item *entry, column int, wrap, base error) (err error) {
+	return item.src.error(a.filename, item.line, column, wrap, base)
+}
+
 func (a *archive) FileName() (filename string) {
 	return a.srcPath
 }
@@ -175,25 +366,41 @@ func (a *archive) FileName() (filename string) {
 func (a *archive) SetBoundary(size int) (err error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
+	err = a.setBoundary(size)
+	return
+}
+
+// setBoundary changes the boundary of this archive and all nested archives,
+// failing without any changes when a nested archive fails to parse
+func (a *archive) setBoundary(size int) (err error) {
 	if size <= 0 {
 		err = ErrBadBoundary
 		return
 	}
 
-	// update embedded boundaries
+	// rewrite nested archives first, nothing is changed on error
+	updates := make(map[*entry]string)
 	for _, item := range a.entries {
-		item.boundary = size
+		if !item.IsHRX() {
+			continue
+		}
 		var ia *archive
-		if ia, err = item.parseHRX(); err == nil {
-			if err = ia.SetBoundary(size + 1); err == nil {
-				updated := ia.String()
-				item.body = &updated
-				a.report(item.GetPathname(), OpBoundary, a.boundary, size)
-			}
+		if ia, err = item.parseNested(); err == nil {
+			err = ia.setBoundary(size + 1)
 		}
 		if err != nil {
 			return
 		}
+		updates[item] = ia.String()
+	}
+
+	// update embedded boundaries
+	for _, item := range a.entries {
+		item.boundary = size
+		if updated, ok := updates[item]; ok {
+			item.body = &updated
+			a.report(item.GetPathname(), OpBoundary, a.boundary, size)
+		}
 	}
 
 	a.report("", OpBoundary, a.boundary, size)
@@ -209,10 +416,16 @@ func (a *archive) GetBoundary() (size int) {
 	return a.boundary
 }
 
-func (a *archive) SetComment(comment string) {
+func (a *archive) SetComment(comment string) (err error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
+	previous := a.comment
 	a.comment = &comment
+	if err = a.raiseBoundary(comment); err != nil {
+		// the comment would collide with the boundary
+		a.comment = previous
+	}
+	return
 }
 
 func (a *archive) GetComment() (comment string, ok bool) {
@@ -233,46 +446,82 @@ func (a *archive) DeleteComment() {
 func (a *archive) Set(pathname, body, comment string) (err error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
+	err = a.set(pathname, body, comment)
+	return
+}
 
+// set is the implementation of Set, without locking the mutex
+func (a *archive) set(pathname, body, comment string) (err error) {
 	if body != "" && !utf8.ValidString(body) {
-		err = a.error(a.lastLine+1, 0, ErrInvalidUnicode, ErrMalformedInput)
+		err = a.error(a.lastLine+1, ErrInvalidUnicode, ErrMalformedInput)
 		return
 	} else if comment != "" && !utf8.ValidString(comment) {
-		err = a.error(a.lastLine+1, 0, ErrInvalidUnicode, ErrMalformedInput)
+		err = a.error(a.lastLine+1, ErrInvalidUnicode, ErrMalformedInput)
+		return
+	}
+
+	if outer, inner, nested := splitNestedPath(pathname); nested {
+		err = a.updateNested(outer, true, func(ia *archive) (err error) {
+			return ia.Set(inner, body, comment)
+		})
 		return
 	}
 
 	var ok bool
 	var this *entry
+	lastLine := a.lastLine
 	if this, ok = a.lookup[pathname]; !ok {
 
 		a.lastLine += 1
 		this = newEntry(a.lastLine, a.boundary, pathname, body, nil)
 		if ia, ee := this.parseHRX(); ee == nil {
-			if ee = ia.SetBoundary(a.boundary + 1); ee != nil {
-				return
-			}
+			// nested archives are stored as-is, even when they contain
+			// errors of their own
 			a.lastLine += ia.lastLine
 		}
 		a.entries = append(a.entries, this)
 		a.lookup[pathname] = this
-
-		a.report(this.GetPathname(), OpAppended, body, comment)
-	} else {
+	}
+	previousBody, previousSep, previousComment := this.body, this.sep, this.comment
+	if ok {
 		this.body = &body
-		a.report(this.GetPathname(), OpUpdated, body, comment)
+		this.sep = false
 	}
 	if comment != "" {
 		this.comment = &comment
 	} else {
 		this.comment = nil
 	}
+
+	if err = a.raiseBoundary(body, comment); err != nil {
+		// the body or comment would collide with the boundary
+		if ok {
+			this.body, this.sep, this.comment = previousBody, previousSep, previousComment
+		} else {
+			a.entries = a.entries[:len(a.entries)-1]
+			delete(a.lookup, pathname)
+			a.lastLine = lastLine
+		}
+		return
+	}
+
+	if ok {
+		a.report(this.GetPathname(), OpUpdated, body, comment)
+	} else {
+		a.report(this.GetPathname(), OpAppended, body, comment)
+	}
 	return
 }
 
 func (a *archive) Get(path string) (body, comment string, ok bool) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
+	if outer, inner, nested := splitNestedPath(path); nested {
+		if ia, err := a.getNested(outer); err == nil {
+			body, comment, ok = ia.Get(inner)
+		}
+		return
+	}
 	var this *entry
 	if this, ok = a.lookup[path]; ok {
 		if this.body != nil {
@@ -288,26 +537,30 @@ func (a *archive) Get(path string) (body, comment string, ok bool) {
 func (a *archive) Delete(path string) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
+	a.delete(path)
+}
+
+// delete is the implementation of Delete, without locking the mutex
+func (a *archive) delete(path string) {
+	if outer, inner, nested := splitNestedPath(path); nested {
+		_ = a.updateNested(outer, false, func(ia *archive) (err error) {
+			if _, _, ok := ia.Get(inner); !ok {
+				return ErrNotFound
+			}
+			ia.Delete(inner)
+			return
+		})
+		return
+	}
 	if this, ok := a.lookup[path]; ok {
 		var list []*entry
-		decrement := -1
-		for idx, item := range a.entries {
-			if item.GetPathname() == this.GetPathname() {
-				if idx+1 < len(a.entries) {
-					next := a.entries[idx+1]
-					decrement = next.line - item.line
-				}
-			} else {
-				if decrement > 0 {
-					item.line -= decrement
-				}
+		for _, item := range a.entries {
+			if item.GetPathname() != this.GetPathname() {
 				list = append(list, item)
 			}
 		}
 		a.entries = list
-		if decrement > 0 {
-			a.lastLine -= decrement
-		}
+		a.renumber()
 		delete(a.lookup, path)
 		a.report(this.GetPathname(), OpDeleted)
 	}
@@ -316,6 +569,13 @@ func (a *archive) Delete(path string) {
 func (a *archive) ParseHRX(path string) (parsed Archive, err error) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
+	if outer, inner, nested := splitNestedPath(path); nested {
+		var ia *archive
+		if ia, err = a.getNested(outer); err == nil {
+			parsed, err = ia.ParseHRX(inner)
+		}
+		return
+	}
 	if item, ok := a.lookup[path]; ok {
 		parsed, err = item.parseHRX()
 		return
@@ -327,6 +587,12 @@ func (a *archive) ParseHRX(path string) (parsed Archive, err error) {
 func (a *archive) Entry(pathname string) Entry {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
+	if outer, inner, nested := splitNestedPath(pathname); nested {
+		if ia, err := a.getNested(outer); err == nil {
+			return ia.Entry(inner)
+		}
+		return nil
+	}
 	if this, ok := a.lookup[pathname]; ok {
 		return this.clone()
 	} else if this, ok = a.lookup[pathname+"/"]; ok {
@@ -366,23 +632,7 @@ func (a *archive) Entries() (entries []Entry) {
 func (a *archive) String() (data string) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
-	last := len(a.entries) - 1
-	for idx, item := range a.entries {
-		data += item.String()
-		if item.IsFile() && item.GetBody() != "" {
-			if idx < last {
-				data += "\n"
-			} else if a.comment != nil {
-				data += "\n"
-			}
-		}
-	}
-	if a.comment != nil {
-		comment := newBoundary(a.boundary, "")
-		comment += *a.comment
-		data += comment
-	}
-	return
+	return a.render()
 }
 
 func (a *archive) WriteFile(destination string) (err error) {
@@ -397,20 +647,7 @@ func (a *archive) WriteFile(destination string) (err error) {
 	var fh *os.File
 	if fh, err = os.OpenFile(destination, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perms); err == nil {
 		defer fh.Close()
-		var contents string
-		last := a.Len() - 1
-		for idx, item := range a.entries {
-			contents += item.String()
-			if (idx < last || a.comment != nil) && item.IsFile() {
-				contents += "\n"
-			}
-		}
-		if a.comment != nil {
-			comment := newBoundary(a.boundary, "")
-			comment += *a.comment
-			contents += comment
-		}
-		_, err = fh.WriteString(contents)
+		_, err = fh.WriteString(a.render())
 	}
 	return
 }
@@ -0,0 +1,498 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultDiffContext is the number of unchanged lines included before and
+// after each change within a DiffHunk
+const DefaultDiffContext = 3
+
+// DiffChange describes how an entry differs between two archives
+type DiffChange string
+
+const (
+	// DiffAdded indicates the entry is only present in the second archive
+	DiffAdded DiffChange = "added"
+	// DiffRemoved indicates the entry is only present in the first archive
+	DiffRemoved DiffChange = "removed"
+	// DiffModified indicates the entry is present in both archives with a
+	// different body or comment
+	DiffModified DiffChange = "modified"
+)
+
+// DiffOptions configures the behaviour of Diff
+type DiffOptions struct {
+	// Recurse compares entries where IsHRX is true as archives, producing
+	// entry differences with nested pathnames instead of line differences
+	Recurse bool
+	// Context is the number of unchanged lines surrounding each change, a
+	// negative value disables context and zero uses DefaultDiffContext
+	Context int
+}
+
+func (o *DiffOptions) recurse() bool {
+	return o != nil && o.Recurse
+}
+
+func (o *DiffOptions) context() int {
+	switch {
+	case o == nil || o.Context == 0:
+		return DefaultDiffContext
+	case o.Context < 0:
+		return 0
+	}
+	return o.Context
+}
+
+// ArchiveDiff is the structured difference between two archives, ignoring
+// boundary sizes and the ordering of entries
+type ArchiveDiff struct {
+	// A is the filename of the first archive
+	A string
+	// B is the filename of the second archive
+	B string
+	// CommentChanged is true when the archive comments differ
+	CommentChanged bool
+	// CommentA is the archive comment of the first archive
+	CommentA string
+	// CommentB is the archive comment of the second archive
+	CommentB string
+	// Entries is the list of entry differences, removed and modified entries
+	// in the order of the first archive followed by added entries in the
+	// order of the second archive
+	Entries []*EntryDiff
+}
+
+// EntryDiff is the difference of a single entry between two archives
+type EntryDiff struct {
+	// Pathname is the pathname of the entry, entries found by recursing into
+	// nested archives use a NestedPath address
+	Pathname string
+	// Change is how this entry differs
+	Change DiffChange
+	// Dir is true when this entry is a directory
+	Dir bool
+	// LineA is the header line number of the entry within the first archive,
+	// zero when the entry was added
+	LineA int
+	// LineB is the header line number of the entry within the second archive,
+	// zero when the entry was removed
+	LineB int
+	// CommentChanged is true when the entry comments differ
+	CommentChanged bool
+	// CommentA is the entry comment within the first archive
+	CommentA string
+	// CommentB is the entry comment within the second archive
+	CommentB string
+	// Hunks are the line differences of the entry bodies, line numbers refer
+	// to the lines of each archive
+	Hunks []*DiffHunk
+}
+
+// DiffHunk is a group of nearby line changes, in the unified diff style
+type DiffHunk struct {
+	// StartA is the first line of this hunk within the first archive
+	StartA int
+	// LinesA is the number of lines of this hunk from the first archive
+	LinesA int
+	// StartB is the first line of this hunk within the second archive
+	StartB int
+	// LinesB is the number of lines of this hunk from the second archive
+	LinesB int
+	// Lines is the list of unchanged, removed and added lines
+	Lines []DiffLine
+}
+
+// DiffLine is a single line of a DiffHunk
+type DiffLine struct {
+	// Op is ' ' for unchanged lines, '-' for removed and '+' for added lines
+	Op byte
+	// Text is the line content, including the trailing newline if present
+	Text string
+}
+
+// Diff compares two archives, returning the added, removed and modified
+// entries along with any comment changes. Entry bodies are compared line by
+// line with all line numbers referring back to the lines of each archive as
+// returned by String. When options.Recurse is true, entries where IsHRX is
+// true and which parse in both archives are compared as archives as well
+func Diff(a, b Archive, options *DiffOptions) (diff *ArchiveDiff, err error) {
+	var aa, ba *archive
+	if aa, err = asArchive(a); err != nil {
+		return
+	} else if ba, err = asArchive(b); err != nil {
+		return
+	}
+
+	aa.mutex.RLock()
+	defer aa.mutex.RUnlock()
+	if ba != aa {
+		ba.mutex.RLock()
+		defer ba.mutex.RUnlock()
+	}
+
+	diff = &ArchiveDiff{A: aa.filename, B: ba.filename}
+	diff.CommentA, diff.CommentB = derefString(aa.comment), derefString(ba.comment)
+	diff.CommentChanged = !equalStrings(aa.comment, ba.comment)
+	diff.Entries, err = diffEntries(aa, ba, "", 0, 0, options)
+	return
+}
+
+// Empty returns true when there are no differences
+func (d *ArchiveDiff) Empty() bool {
+	return !d.CommentChanged && len(d.Entries) == 0
+}
+
+// Unified returns all entry differences in the unified diff format, with an
+// added "@@@ comment @@@" section for changed comments
+func (d *ArchiveDiff) Unified() (text string) {
+	var buf strings.Builder
+	if d.CommentChanged {
+		buf.WriteString("--- " + d.A + "\n+++ " + d.B + "\n")
+		writeCommentDiff(&buf, d.CommentA, d.CommentB)
+	}
+	for _, ed := range d.Entries {
+		buf.WriteString(ed.Unified())
+	}
+	return buf.String()
+}
+
+// Unified returns this entry difference in the unified diff format, using
+// "/dev/null" as the pathname of the missing side for added and removed
+// entries
+func (e *EntryDiff) Unified() (text string) {
+	nameA, nameB := "a/"+e.Pathname, "b/"+e.Pathname
+	switch e.Change {
+	case DiffAdded:
+		nameA = "/dev/null"
+	case DiffRemoved:
+		nameB = "/dev/null"
+	}
+
+	var buf strings.Builder
+	buf.WriteString("--- " + nameA + "\n+++ " + nameB + "\n")
+	if e.CommentChanged {
+		writeCommentDiff(&buf, e.CommentA, e.CommentB)
+	}
+	for _, hunk := range e.Hunks {
+		buf.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", hunk.StartA, hunk.LinesA, hunk.StartB, hunk.LinesB))
+		for _, line := range hunk.Lines {
+			buf.WriteByte(line.Op)
+			buf.WriteString(line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+func writeCommentDiff(buf *strings.Builder, a, b string) {
+	buf.WriteString("@@@ comment @@@\n")
+	for _, line := range splitLines(a) {
+		buf.WriteString("-" + strings.TrimSuffix(line, "\n") + "\n")
+	}
+	for _, line := range splitLines(b) {
+		buf.WriteString("+" + strings.TrimSuffix(line, "\n") + "\n")
+	}
+}
+
+// asArchive returns the *archive of the Archive given, parsing the String
+// output of any other Archive implementation
+func asArchive(a Archive) (hrx *archive, err error) {
+	if v, ok := a.(*archive); ok {
+		return v, nil
+	}
+	hrx, err = parseData(a.FileName(), a.String())
+	return
+}
+
+// diffEntries compares the entries of two archives, prefixing pathnames and
+// offsetting line numbers for nested archives
+func diffEntries(aa, ba *archive, prefix string, offsetA, offsetB int, options *DiffOptions) (entries []*EntryDiff, err error) {
+	linesA, linesB := aa.headerLines(), ba.headerLines()
+	pathname := func(name string) string {
+		if prefix != "" {
+			return NestedPath(prefix, name)
+		}
+		return name
+	}
+
+	for _, ae := range aa.entries {
+		name := ae.GetPathname()
+		if name == "" {
+			continue
+		}
+		be, present := ba.lookup[name]
+		if !present {
+			ed := &EntryDiff{
+				Pathname: pathname(name),
+				Change:   DiffRemoved,
+				Dir:      ae.IsDir(),
+				LineA:    offsetA + linesA[name],
+				CommentA: ae.GetComment(),
+			}
+			ed.CommentChanged = ae.comment != nil
+			ed.Hunks = diffHunks(ae.GetBody(), "", ed.LineA+1, 0, options.context())
+			entries = append(entries, ed)
+			continue
+		}
+
+		ed := &EntryDiff{
+			Pathname: pathname(name),
+			Change:   DiffModified,
+			Dir:      ae.IsDir(),
+			LineA:    offsetA + linesA[name],
+			LineB:    offsetB + linesB[name],
+			CommentA: ae.GetComment(),
+			CommentB: be.GetComment(),
+		}
+		ed.CommentChanged = !equalStrings(ae.comment, be.comment)
+
+		var nested []*EntryDiff
+		if ae.IsHRX() && be.IsHRX() && ae.GetBody() != be.GetBody() {
+			// nested archives which only differ by boundaries are equal
+			ia, iaErr := ae.parseNested()
+			ib, ibErr := be.parseNested()
+			if iaErr == nil && ibErr == nil {
+				if nested, err = diffEntries(ia, ib, ed.Pathname, ed.LineA, ed.LineB, options); err != nil {
+					return
+				}
+				commentChanged := !equalStrings(ia.comment, ib.comment)
+				if !options.recurse() {
+					if len(nested) > 0 || commentChanged {
+						ed.Hunks = diffHunks(ae.GetBody(), be.GetBody(), ed.LineA+1, ed.LineB+1, options.context())
+					}
+					nested = nil
+				} else if commentChanged {
+					// the nested archive comment is reported as the entry body
+					ed.Hunks = diffHunks(derefString(ia.comment), derefString(ib.comment), ed.LineA+ia.commentLine(), ed.LineB+ib.commentLine(), options.context())
+				}
+			} else {
+				ed.Hunks = diffHunks(ae.GetBody(), be.GetBody(), ed.LineA+1, ed.LineB+1, options.context())
+			}
+		} else if ae.GetBody() != be.GetBody() {
+			ed.Hunks = diffHunks(ae.GetBody(), be.GetBody(), ed.LineA+1, ed.LineB+1, options.context())
+		}
+
+		if ed.CommentChanged || len(ed.Hunks) > 0 {
+			entries = append(entries, ed)
+		}
+		entries = append(entries, nested...)
+	}
+
+	for _, be := range ba.entries {
+		name := be.GetPathname()
+		if name == "" {
+			continue
+		} else if _, present := aa.lookup[name]; present {
+			continue
+		}
+		ed := &EntryDiff{
+			Pathname: pathname(name),
+			Change:   DiffAdded,
+			Dir:      be.IsDir(),
+			LineB:    offsetB + linesB[name],
+			CommentB: be.GetComment(),
+		}
+		ed.CommentChanged = be.comment != nil
+		ed.Hunks = diffHunks("", be.GetBody(), 0, ed.LineB+1, options.context())
+		entries = append(entries, ed)
+	}
+
+	return
+}
+
+// headerLines returns the line number of each entry header, as output by
+// String
+func (a *archive) headerLines() (lines map[string]int) {
+	lines = make(map[string]int)
+	nodes, _ := a.syntax()
+	for _, node := range nodes {
+		if node.Kind == SyntaxHeader {
+			lines[node.Pathname] = node.Line
+		}
+	}
+	return
+}
+
+// commentLine returns the line number of the first line of the archive
+// comment, as output by String
+func (a *archive) commentLine() (line int) {
+	nodes, lines := a.syntax()
+	for idx := len(nodes) - 1; idx >= 0; idx-- {
+		if node := nodes[idx]; node.Kind == SyntaxBoundary && node.Pathname == "" {
+			// skipping the comment boundary line
+			return node.Line + 1
+		}
+	}
+	return lines + 1
+}
+
+func derefString(value *string) string {
+	if value != nil {
+		return *value
+	}
+	return ""
+}
+
+func equalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// splitLines splits the text into lines, each including the trailing newline
+// if present
+func splitLines(text string) (lines []string) {
+	if text == "" {
+		return
+	}
+	lines = strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return
+}
+
+// diffHunks compares the lines of two texts, producing hunks with the context
+// given. The start arguments are the line numbers of the first line of each
+// text
+func diffHunks(a, b string, startA, startB, context int) (hunks []*DiffHunk) {
+	linesA, linesB := splitLines(a), splitLines(b)
+	ops := diffLines(linesA, linesB)
+
+	var changes []int
+	for idx, op := range ops {
+		if op.Op != ' ' {
+			changes = append(changes, idx)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	// group changes which are within two contexts of each other
+	var groups [][2]int
+	first, last := changes[0], changes[0]
+	for _, idx := range changes[1:] {
+		if idx-last > 2*context+1 {
+			groups = append(groups, [2]int{first, last})
+			first = idx
+		}
+		last = idx
+	}
+	groups = append(groups, [2]int{first, last})
+
+	// lineA and lineB are the number of lines of each text before ops[idx]
+	lineA, lineB := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for idx, op := range ops {
+		lineA[idx+1], lineB[idx+1] = lineA[idx], lineB[idx]
+		if op.Op != '+' {
+			lineA[idx+1] += 1
+		}
+		if op.Op != '-' {
+			lineB[idx+1] += 1
+		}
+	}
+
+	for _, group := range groups {
+		lo, hi := max(group[0]-context, 0), min(group[1]+context+1, len(ops))
+		hunk := &DiffHunk{
+			StartA: startA + lineA[lo],
+			LinesA: lineA[hi] - lineA[lo],
+			StartB: startB + lineB[lo],
+			LinesB: lineB[hi] - lineB[lo],
+			Lines:  ops[lo:hi],
+		}
+		// unified diffs refer to the line before when there are no lines
+		if hunk.LinesA == 0 {
+			hunk.StartA = max(hunk.StartA-1, 0)
+		}
+		if hunk.LinesB == 0 {
+			hunk.StartB = max(hunk.StartB-1, 0)
+		}
+		hunks = append(hunks, hunk)
+	}
+	return
+}
+
+// diffLines returns the shortest edit script between the two lists of lines
+// using the Myers difference algorithm
+func diffLines(a, b []string) (ops []DiffLine) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		// only the diagonals reachable from this step are kept, -d-1 to d+1
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// backtrack through the trace, collecting operations in reverse
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd, base := trace[d], d+1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && vd[base+k-1] < vd[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vd[base+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, DiffLine{Op: ' ', Text: a[x-1]})
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, DiffLine{Op: '+', Text: b[y-1]})
+			} else {
+				ops = append(ops, DiffLine{Op: '-', Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	tDiffA = `<===> same.txt
+unchanged
+<===>
+old comment
+<===> changed.txt
+one
+two
+three
+<===> removed.txt
+gone
+<===> dir/
+<===> nested.hrx
+<====> inner.txt
+inner one
+<====> inner-removed.txt
+bye
+`
+	tDiffB = `<=====> changed.txt
+one
+2
+three
+<=====> dir/
+<=====> same.txt
+unchanged
+<=====> added.txt
+new
+<=====> nested.hrx
+<======> inner.txt
+inner two
+`
+)
+
+func TestDiff(t *testing.T) {
+	Convey("Diff", t, func() {
+		a, err := ParseData("a.hrx", tDiffA)
+		So(err, ShouldBeNil)
+		b, err := ParseData("b.hrx", tDiffB)
+		So(err, ShouldBeNil)
+
+		Convey("identical archives", func() {
+			c, ee := ParseData("c.hrx", tDiffA)
+			So(ee, ShouldBeNil)
+			So(c.SetBoundary(7), ShouldBeNil)
+			d, ee := Diff(a, c, nil)
+			So(ee, ShouldBeNil)
+			So(d.Empty(), ShouldBeTrue)
+			So(d.Unified(), ShouldEqual, "")
+		})
+
+		Convey("entry changes", func() {
+			d, ee := Diff(a, b, nil)
+			So(ee, ShouldBeNil)
+			So(d.Empty(), ShouldBeFalse)
+			So(d.CommentChanged, ShouldBeFalse)
+			So(d.Entries, ShouldHaveLength, 4)
+
+			changed := d.Entries[0]
+			So(changed.Pathname, ShouldEqual, "changed.txt")
+			So(changed.Change, ShouldEqual, DiffModified)
+			So(changed.LineA, ShouldEqual, 5)
+			So(changed.LineB, ShouldEqual, 1)
+			So(changed.CommentChanged, ShouldBeTrue)
+			So(changed.CommentA, ShouldEqual, "old comment\n")
+			So(changed.CommentB, ShouldEqual, "")
+			So(changed.Hunks, ShouldHaveLength, 1)
+			So(changed.Hunks[0].StartA, ShouldEqual, 6)
+			So(changed.Hunks[0].StartB, ShouldEqual, 2)
+			So(changed.Unified(), ShouldEqual, `--- a/changed.txt
++++ b/changed.txt
+@@@ comment @@@
+-old comment
+@@ -6,3 +2,3 @@
+ one
+-two
++2
+ three
+\ No newline at end of file
+`)
+
+			So(d.Entries[1].Pathname, ShouldEqual, "removed.txt")
+			So(d.Entries[1].Change, ShouldEqual, DiffRemoved)
+			So(d.Entries[1].LineA, ShouldEqual, 9)
+			So(d.Entries[2].Pathname, ShouldEqual, "nested.hrx")
+			So(d.Entries[2].Change, ShouldEqual, DiffModified)
+			So(d.Entries[3].Pathname, ShouldEqual, "added.txt")
+			So(d.Entries[3].Change, ShouldEqual, DiffAdded)
+			So(d.Entries[3].LineB, ShouldEqual, 8)
+			So(d.Entries[3].Unified(), ShouldEqual, "--- /dev/null\n+++ b/added.txt\n@@ -0,0 +9,1 @@\n+new\n\\ No newline at end of file\n")
+		})
+
+		Convey("archive comments and directories", func() {
+			So(a.SetComment("first"), ShouldBeNil)
+			b.Delete("dir/")
+			d, ee := Diff(a, b, nil)
+			So(ee, ShouldBeNil)
+			So(d.CommentChanged, ShouldBeTrue)
+			So(d.CommentA, ShouldEqual, "first")
+			var dirs []*EntryDiff
+			for _, ed := range d.Entries {
+				if ed.Dir {
+					dirs = append(dirs, ed)
+				}
+			}
+			So(dirs, ShouldHaveLength, 1)
+			So(dirs[0].Pathname, ShouldEqual, "dir/")
+			So(dirs[0].Change, ShouldEqual, DiffRemoved)
+		})
+
+		Convey("recurse into nested archives", func() {
+			d, ee := Diff(a, b, &DiffOptions{Recurse: true, Context: -1})
+			So(ee, ShouldBeNil)
+			var pathnames []string
+			for _, ed := range d.Entries {
+				pathnames = append(pathnames, ed.Pathname)
+			}
+			So(pathnames, ShouldEqual, []string{"changed.txt", "removed.txt", "nested.hrx//inner.txt", "nested.hrx//inner-removed.txt", "added.txt"})
+			inner := d.Entries[2]
+			So(inner.LineA, ShouldEqual, 13)
+			So(inner.LineB, ShouldEqual, 11)
+			So(inner.Hunks[0].StartA, ShouldEqual, 14)
+			So(inner.Hunks[0].StartB, ShouldEqual, 12)
+			So(inner.Hunks[0].Lines, ShouldEqual, []DiffLine{{Op: '-', Text: "inner one"}, {Op: '+', Text: "inner two\n"}})
+		})
+
+		Convey("line numbers follow the syntax layout", func() {
+			x, ee := ParseData("x.hrx", "<===> a\n\n<===> b\nx\n")
+			So(ee, ShouldBeNil)
+			y, ee := ParseData("y.hrx", "<===> a\n\n<===> b\ny\n")
+			So(ee, ShouldBeNil)
+			d, ee := Diff(x, y, nil)
+			So(ee, ShouldBeNil)
+			So(d.Entries, ShouldHaveLength, 1)
+			So(d.Entries[0].LineA, ShouldEqual, 3)
+			So(d.Entries[0].Hunks[0].StartA, ShouldEqual, 4)
+			for _, node := range x.Syntax() {
+				if node.Pathname == "b" && node.Kind == SyntaxBody {
+					So(node.Line, ShouldEqual, d.Entries[0].Hunks[0].StartA)
+				}
+			}
+		})
+
+		Convey("line differences", func() {
+			So(diffLines(nil, nil), ShouldBeEmpty)
+			ops := diffLines([]string{"a", "b", "c", "d"}, []string{"a", "c", "d", "e"})
+			So(ops, ShouldEqual, []DiffLine{{' ', "a"}, {'-', "b"}, {' ', "c"}, {' ', "d"}, {'+', "e"}})
+			hunks := diffHunks("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n", 1, 1, 1)
+			So(hunks, ShouldHaveLength, 1)
+			So(hunks[0].StartA, ShouldEqual, 10)
+			So(hunks[0].LinesA, ShouldEqual, 1)
+			So(hunks[0].LinesB, ShouldEqual, 2)
+
+			// every edit script reproduces both sides
+			random := rand.New(rand.NewSource(1))
+			lines := func() (list []string) {
+				for i := random.Intn(40); i > 0; i-- {
+					list = append(list, string(rune('a'+random.Intn(4))))
+				}
+				return
+			}
+			for i := 0; i < 200; i++ {
+				a, b := lines(), lines()
+				var gotA, gotB []string
+				for _, op := range diffLines(a, b) {
+					if op.Op != '+' {
+						gotA = append(gotA, op.Text)
+					}
+					if op.Op != '-' {
+						gotB = append(gotB, op.Text)
+					}
+				}
+				So(gotA, ShouldEqual, a)
+				So(gotB, ShouldEqual, b)
+			}
+		})
+
+	})
+}
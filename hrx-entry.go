@@ -39,21 +39,16 @@ type Entry interface {
 
 	// ParseHRX checks if this entry IsHRX and parses the body content into a
 	// new Archive instance. Note that modifying the parsed Archive will not
-	// update this specific entry's body content
+	// update this specific entry's body content, use a nested address with
+	// the parent Archive methods instead
 	//
 	// Example of modifying and saving a nested Archive instance:
 	//
 	//  a := ParseData("example.hrx", ...)
-	//  if entry := a.GetEntry("nested.hrx"); entry != nil {
-	//      if b, err := entry.ParseHRX(); err != nil {
-	//          ... handle error ...
-	//      } else {
-	//          b.Set("filename", "new contents", "")
-	//          a.Set("nested.hrx", b.GetBody(), b.GetComment())
-	//          if err = a.WriteFile("example.hrx"); err != nil {
-	//              ... handle error ...
-	//          }
-	//      }
+	//  if err := a.Set(NestedPath("nested.hrx", "filename"), "new contents", ""); err != nil {
+	//      ... handle error ...
+	//  } else if err = a.WriteFile("example.hrx"); err != nil {
+	//      ... handle error ...
 	//  }
 	//
 	ParseHRX() (a Archive, err error)
@@ -84,6 +79,17 @@ type entry struct {
 	body *string
 	// comment is a pointer to an optional comment associated with the pathname
 	comment *string
+	// sep records if the newline preceding the next boundary was present in
+	// the parsed source when the body is empty
+	sep bool
+	// src is the header line as parsed, used for positioning diagnostics
+	src *headerSource
+	// header and commentHeader are the boundary lines of this entry and its
+	// comment as parsed, including any newline, see boundaryLine
+	header        string
+	commentHeader string
+	// invalid is the error for an invalid pathname, reported by finalize
+	invalid error
 
 	err error
 }
@@ -109,6 +115,7 @@ func (e *entry) clone() (cloned *entry) {
 		hrx:      e.hrx,
 		line:     e.line,
 		boundary: e.boundary,
+		sep:      e.sep,
 		err:      e.err,
 	}
 	mkr := func(v string) *string { return &v }
@@ -17,11 +17,17 @@ package hrx
 import (
 	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
 )
 
 // General package errors
 var (
-	ErrDstIsFile = errors.New("destination directory is a file")
+	ErrDstIsFile         = errors.New("destination directory is a file")
+	ErrWriterStarted     = errors.New("writer has already started")
+	ErrWriteBeforeHeader = errors.New("write before header")
+	ErrWriteAfterClose   = errors.New("write after close")
 )
 
 // HRX specification errors
@@ -39,6 +45,8 @@ var (
 	ErrNotFound          = errors.New("pathname not found")
 	ErrEmptyArchive      = errors.New("empty archive")
 	ErrNotAnArchive      = errors.New("not an archive (.hrx)")
+	ErrNotADirectory     = errors.New("not a directory")
+	ErrIndexOutOfRange   = errors.New("index out of range")
 	ErrBadArchiveHeader  = errors.New("bad archive header")
 	ErrBadBoundary       = errors.New("bad archive boundary")
 	ErrNoSpaceBeforePath = errors.New("no space before pathname")
@@ -48,6 +56,10 @@ var (
 	ErrContainsColon     = errors.New("pathname contains a colon")
 	ErrEscapeCharacter   = errors.New("pathname contains escape characters")
 	ErrContainsRelPath   = errors.New("pathname contains relative names (//, . or ..)")
+	ErrBoundaryCollision = errors.New("contains a line matching the boundary")
+	ErrBinaryContent     = errors.New("contains binary content")
+	ErrUnsupportedMember = errors.New("unsupported archive member type")
+	ErrTxtarMarker       = errors.New("contains a line matching a txtar file marker")
 )
 
 // Error is the type for all error instances returned from this package
@@ -60,7 +72,33 @@ type Error struct {
 	Wrap error
 	// Line is the line number of the archive where this error has occurred
 	Line int
-	// Offset is the column number of the line of the archive where this error has occurred
+	// Column is the column number, counted in runes starting from one, of
+	// the line where this error has occurred, zero when not known
+	Column int
+	// Offset is the byte offset within the archive of the Column, only
+	// meaningful when Column is not zero
+	Offset int
+	// Source is the line of the archive where this error has occurred,
+	// without the trailing newline, empty when not known
+	Source string
+}
+
+// errorHints are the suggestions given by Error.Hint
+var errorHints = map[error]string{
+	ErrBadArchiveHeader:     "archives must start with a boundary line, such as: <===> file.txt",
+	ErrBadBoundary:          "boundaries need at least one equals sign, such as: <=>",
+	ErrNoSpaceBeforePath:    "separate the boundary and the pathname with a single space",
+	ErrInvalidCharRange:     "pathnames cannot contain control characters",
+	ErrContainsColon:        "pathnames cannot contain colons",
+	ErrEscapeCharacter:      "pathnames cannot contain backslashes, use forward slashes",
+	ErrStartsWithDirSep:     "pathnames are relative, remove the leading slash",
+	ErrContainsRelPath:      "remove the empty, \".\" or \"..\" path components",
+	ErrDuplicatePath:        "each pathname can only be used once per archive",
+	ErrFileAsParentDir:      "a pathname cannot be both a file and a directory",
+	ErrSequentialComments:   "combine the comments into one, or remove one of them",
+	ErrDirectoryHasContents: "directories cannot have a body, remove the lines following this one",
+	ErrBoundaryCollision:    "use a longer boundary, see MinimalBoundary",
+	ErrInvalidUnicode:       "archives must be valid utf-8 text",
 }
 
 // AsError is a convenience wrapper around errors.As for an *Error
@@ -78,6 +116,22 @@ func newError(file string, line int, wrap, base error) (err *Error) {
 	}
 }
 
+// at positions this error at the column of the source line given, where
+// offset is the byte offset of the start of the line within the archive
+func (e *Error) at(source string, offset, column int) *Error {
+	e.Source = source
+	if column > 0 {
+		e.Column = column
+		e.Offset = offset
+		for idx := 1; idx < column && source != ""; idx++ {
+			_, size := utf8.DecodeRuneInString(source)
+			e.Offset += size
+			source = source[size:]
+		}
+	}
+	return e
+}
+
 func (e *Error) Is(err error) (ok bool) {
 	ok = errors.Is(e.Base, err) ||
 		errors.Is(e.Wrap, err)
@@ -90,3 +144,83 @@ func (e *Error) Error() string {
 	}
 	return fmt.Sprintf(`%s:%d  %v`, e.File, e.Line, e.Base)
 }
+
+// Hint returns a suggestion for resolving this error, if there is one
+func (e *Error) Hint() (hint string) {
+	for _, err := range []error{e.Wrap, e.Base} {
+		for key, text := range errorHints {
+			if err != nil && errors.Is(err, key) {
+				return text
+			}
+		}
+	}
+	return
+}
+
+// Format implements fmt.Formatter. The %+v verb renders the Error message
+// followed by the Source line with a caret marking the Column and any Hint,
+// all other verbs render the Error message
+func (e *Error) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		_, _ = io.WriteString(f, e.Error()+e.snippet())
+	case verb == 'q':
+		_, _ = fmt.Fprintf(f, "%q", e.Error())
+	default:
+		_, _ = io.WriteString(f, e.Error())
+	}
+}
+
+// snippet renders the Source line, caret and Hint of this error
+func (e *Error) snippet() (text string) {
+	gutter := fmt.Sprintf("%d", e.Line)
+	blank := strings.Repeat(" ", len(gutter))
+	if e.Source != "" {
+		text += fmt.Sprintf("\n%s | %s", gutter, e.Source)
+		if e.Column > 0 {
+			text += fmt.Sprintf("\n%s | %s^", blank, strings.Repeat(" ", e.Column-1))
+		}
+	}
+	if hint := e.Hint(); hint != "" {
+		text += fmt.Sprintf("\n%s = hint: %s", blank, hint)
+	}
+	return
+}
+
+// headerSource is the position and contents of a header line as parsed, used
+// for positioning diagnostics
+type headerSource struct {
+	// line is the line number of the header
+	line int
+	// offset is the byte offset of the start of the header
+	offset int
+	// text is the header line without the trailing newline
+	text string
+	// boundary is the size of the boundary of the header
+	boundary int
+	// column is the column of any error found while scanning the header
+	column int
+}
+
+// pathColumn returns the column of the byte index within the pathname, the
+// pathname starts after the boundary and the space following it
+func (h *headerSource) pathColumn(index int) (column int) {
+	if h == nil {
+		return
+	}
+	column = h.boundary + 4
+	if start := h.boundary + 3; start <= len(h.text) {
+		pathname := h.text[start:]
+		column += utf8.RuneCountInString(pathname[:min(index, len(pathname))])
+	}
+	return
+}
+
+// error returns a new *Error positioned at the column of this header, a nil
+// headerSource returns an *Error with only the line given
+func (h *headerSource) error(file string, line, column int, wrap, base error) (err *Error) {
+	if h == nil {
+		return newError(file, line, wrap, base)
+	}
+	return newError(file, h.line, wrap, base).at(h.text, h.offset, column)
+}
@@ -16,7 +16,9 @@ package hrx
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -41,6 +43,60 @@ func TestUtilities(t *testing.T) {
 			So(v, ShouldNotBeNil)
 		})
 
+		Convey("Error positions", func() {
+			for _, test := range []struct {
+				input  string
+				line   int
+				column int
+				offset int
+				source string
+				err    error
+			}{
+				{"<===> a\n<===> b:c\n", 2, 8, 15, "<===> b:c", ErrContainsColon},
+				{"<===> a\n<===>b\n", 2, 6, 13, "<===>b", ErrNoSpaceBeforePath},
+				{"<===> é\n<===> é/../b\n", 2, 9, 18, "<===> é/../b", ErrContainsRelPath},
+				{"<===> a\n<===> a\n", 2, 7, 14, "<===> a", ErrDuplicatePath},
+				{"<===> /a\n", 1, 7, 6, "<===> /a", ErrStartsWithDirSep},
+				{"not an archive\n", 1, 1, 0, "not an archive", ErrBadArchiveHeader},
+			} {
+				_, err := ParseData("position.hrx", test.input)
+				So(err, ShouldWrap, test.err)
+				e, ok := AsError(err)
+				So(ok, ShouldBeTrue)
+				So(e.Line, ShouldEqual, test.line)
+				So(e.Column, ShouldEqual, test.column)
+				So(e.Offset, ShouldEqual, test.offset)
+				So(e.Source, ShouldEqual, test.source)
+
+				// the streaming Reader reports the same positions
+				r := NewReader("position.hrx", strings.NewReader(test.input))
+				for err = nil; err == nil; _, err = r.Next() {
+				}
+				re, ok := AsError(err)
+				So(ok, ShouldBeTrue)
+				So(re.Column, ShouldEqual, e.Column)
+				So(re.Offset, ShouldEqual, e.Offset)
+			}
+		})
+
+		Convey("Error.Format", func() {
+			_, err := ParseData("format.hrx", "<===> a\n<===> b\tc\n")
+			So(err, ShouldNotBeNil)
+			So(fmt.Sprintf("%v", err), ShouldEqual, err.Error())
+			So(fmt.Sprintf("%s", err), ShouldEqual, err.Error())
+			So(fmt.Sprintf("%q", err), ShouldEqual, fmt.Sprintf("%q", err.Error()))
+			So(fmt.Sprintf("%+v", err), ShouldEqual, err.Error()+"\n"+
+				"2 | <===> b\tc\n"+
+				"  |        ^\n"+
+				"  = hint: pathnames cannot contain control characters",
+			)
+
+			e := newError("hint.hrx", 12, nil, ErrSequentialComments)
+			So(e.Hint(), ShouldEqual, "combine the comments into one, or remove one of them")
+			So(fmt.Sprintf("%+v", e), ShouldEqual, e.Error()+"\n   = hint: "+e.Hint())
+			So(newError("none.hrx", 1, nil, ErrNotFound).Hint(), ShouldEqual, "")
+		})
+
 		Convey("Error.Is", func() {
 			err := newError("test.hrx", 1, ErrInvalidUnicode, ErrMalformedInput)
 			So(err.Is(ErrInvalidUnicode), ShouldBeTrue)
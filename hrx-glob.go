@@ -0,0 +1,97 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"path"
+	"strings"
+)
+
+// SelectFn is the predicate signature used to select archive entries
+type SelectFn func(e Entry) (selected bool)
+
+// MatchGlob reports whether the pathname matches the glob pattern given.
+// Patterns use the path.Match syntax for each path component, with the
+// following additions:
+//
+//   - a pattern always matches a pathname that is exactly the same
+//   - a "**" path component matches zero or more path components
+//   - a pattern ending with a slash matches directories and everything
+//     within them, for example "dir/" matches "dir/" and "dir/sub/file.txt"
+//
+// Malformed patterns never match anything
+func MatchGlob(pattern, pathname string) (matched bool) {
+	if pattern == pathname {
+		return true
+	}
+
+	isDir := strings.HasSuffix(pathname, "/")
+	names := strings.Split(strings.TrimSuffix(pathname, "/"), "/")
+
+	if dir := strings.TrimSuffix(pattern, "/"); dir != pattern {
+		// match any parent directory, or this directory
+		limit := len(names) - 1
+		if isDir {
+			limit = len(names)
+		}
+		patterns := strings.Split(dir, "/")
+		for count := 1; count <= limit; count++ {
+			if matchComponents(patterns, names[:count]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return matchComponents(strings.Split(pattern, "/"), names)
+}
+
+// matchComponents matches the pattern components with the name components,
+// expanding any "**" pattern components
+func matchComponents(patterns, names []string) (matched bool) {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for skip := 0; skip <= len(names); skip++ {
+				if matchComponents(patterns[1:], names[skip:]) {
+					return true
+				}
+			}
+			return false
+		} else if len(names) == 0 {
+			return false
+		} else if ok, _ := path.Match(patterns[0], names[0]); !ok {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// Glob returns a SelectFn which selects entries with pathnames matching any
+// of the patterns given, see MatchGlob for the pattern syntax
+func Glob(patterns ...string) SelectFn {
+	return func(e Entry) (selected bool) {
+		return matchAnyGlob(patterns, e.GetPathname())
+	}
+}
+
+func matchAnyGlob(patterns []string, pathname string) (matched bool) {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, pathname) {
+			return true
+		}
+	}
+	return
+}
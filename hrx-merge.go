@@ -0,0 +1,462 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+)
+
+// ConflictKind describes why an entry could not be merged cleanly
+type ConflictKind string
+
+const (
+	// ConflictContent indicates both sides changed the same lines of an entry
+	// body, the merged body contains conflict markers
+	ConflictContent ConflictKind = "content"
+	// ConflictDeleteModify indicates one side deleted an entry which the
+	// other side modified, the modified entry is kept
+	ConflictDeleteModify ConflictKind = "delete/modify"
+	// ConflictRename indicates both sides renamed an entry to different
+	// pathnames, the pathname of ours is kept
+	ConflictRename ConflictKind = "rename/rename"
+	// ConflictComment indicates both sides changed a comment differently, the
+	// comment of ours is kept. Archive comment conflicts have an empty
+	// Pathname
+	ConflictComment ConflictKind = "comment"
+)
+
+// MergeOptions configures the behaviour of Merge
+type MergeOptions struct {
+	// OursLabel is the label used for the "<<<<<<<" conflict marker, defaults
+	// to "ours"
+	OursLabel string
+	// TheirsLabel is the label used for the ">>>>>>>" conflict marker,
+	// defaults to "theirs"
+	TheirsLabel string
+}
+
+func (o *MergeOptions) oursLabel() string {
+	if o == nil || o.OursLabel == "" {
+		return "ours"
+	}
+	return o.OursLabel
+}
+
+func (o *MergeOptions) theirsLabel() string {
+	if o == nil || o.TheirsLabel == "" {
+		return "theirs"
+	}
+	return o.TheirsLabel
+}
+
+// MergeConflict is a single conflict found during Merge
+type MergeConflict struct {
+	// Pathname is the pathname of the entry within the merged archive
+	Pathname string
+	// Kind is the type of conflict
+	Kind ConflictKind
+	// Line is the header line number of the entry within the merged archive
+	Line int
+}
+
+// mergeVersion is a single side's version of a merged entry
+type mergeVersion struct {
+	item  *entry
+	index int
+}
+
+func (v *mergeVersion) present() bool {
+	return v != nil && v.item != nil
+}
+
+func (v *mergeVersion) body() *string {
+	if v.present() && !v.item.IsDir() {
+		body := v.item.GetBody()
+		return &body
+	}
+	return nil
+}
+
+func (v *mergeVersion) comment() *string {
+	if v.present() {
+		return v.item.comment
+	}
+	return nil
+}
+
+// mergeItem is a logical entry across all three archives
+type mergeItem struct {
+	pathname string
+	base     *mergeVersion
+	ours     *mergeVersion
+	theirs   *mergeVersion
+}
+
+// Merge performs a three-way merge of the ours and theirs archives, relative
+// to their common base archive. Entries are merged by pathname, with renames
+// detected by identical contents, and the order of ours is kept with entries
+// added by theirs inserted after their preceding entry. Entry bodies changed
+// on both sides are merged line by line, overlapping changes produce a
+// ConflictContent with conflict markers in the merged body. The boundary of
+// ours is used for the merged archive, raised as needed. Any conflicts found
+// are returned along with the merged archive
+func Merge(base, ours, theirs Archive, options *MergeOptions) (merged Archive, conflicts []*MergeConflict, err error) {
+	var ba, oa, ta *archive
+	if ba, err = asArchive(base); err != nil {
+		return
+	} else if oa, err = asArchive(ours); err != nil {
+		return
+	} else if ta, err = asArchive(theirs); err != nil {
+		return
+	}
+
+	locked := make(map[*archive]struct{})
+	for _, a := range []*archive{ba, oa, ta} {
+		if _, present := locked[a]; !present {
+			locked[a] = struct{}{}
+			a.mutex.RLock()
+			defer a.mutex.RUnlock()
+		}
+	}
+
+	items, renamed := mergeItems(ba, oa, ta)
+	for _, item := range renamed {
+		conflicts = append(conflicts, &MergeConflict{Pathname: item.pathname, Kind: ConflictRename})
+	}
+
+	result := newArchive(oa.filename, "")
+	result.boundary = oa.boundary
+
+	comment, ok := merge3(ba.comment, oa.comment, ta.comment)
+	if !ok {
+		conflicts = append(conflicts, &MergeConflict{Kind: ConflictComment})
+	}
+	if comment != nil {
+		if err = result.SetComment(*comment); err != nil {
+			return
+		}
+	}
+
+	for _, item := range items {
+		var body, comment *string
+		switch {
+
+		case !item.ours.present() && !item.theirs.present():
+			// deleted by both
+			continue
+
+		case !item.ours.present() || !item.theirs.present():
+			side := item.ours
+			if !side.present() {
+				side = item.theirs
+			}
+			if item.base.present() {
+				if mergeUnchanged(item.base, side) {
+					// deleted by one side, unchanged by the other
+					continue
+				}
+				conflicts = append(conflicts, &MergeConflict{Pathname: item.pathname, Kind: ConflictDeleteModify})
+			}
+			body, comment = side.body(), side.comment()
+
+		default:
+			var ok bool
+			if body, ok = merge3(item.base.body(), item.ours.body(), item.theirs.body()); !ok {
+				var clean bool
+				var text string
+				if text, clean = mergeLines(derefString(item.base.body()), *item.ours.body(), *item.theirs.body(), options); !clean {
+					conflicts = append(conflicts, &MergeConflict{Pathname: item.pathname, Kind: ConflictContent})
+				}
+				body = &text
+			}
+			if comment, ok = merge3(item.base.comment(), item.ours.comment(), item.theirs.comment()); !ok {
+				conflicts = append(conflicts, &MergeConflict{Pathname: item.pathname, Kind: ConflictComment})
+			}
+		}
+
+		if err = result.Set(item.pathname, derefString(body), derefString(comment)); err != nil {
+			return
+		}
+	}
+
+	lines := result.headerLines()
+	for _, conflict := range conflicts {
+		conflict.Line = lines[conflict.Pathname]
+	}
+
+	merged = result
+	return
+}
+
+func mergeUnchanged(base, side *mergeVersion) bool {
+	return equalStrings(base.body(), side.body()) && equalStrings(base.comment(), side.comment())
+}
+
+// merge3 resolves a three-way change of a single value, returning false when
+// ours and theirs both changed the value differently, in which case the value
+// of ours is returned
+func merge3(base, ours, theirs *string) (value *string, ok bool) {
+	switch {
+	case equalStrings(ours, theirs):
+		return ours, true
+	case equalStrings(base, ours):
+		return theirs, true
+	case equalStrings(base, theirs):
+		return ours, true
+	}
+	return ours, false
+}
+
+// mergeItems pairs up the entries of the three archives, detecting renames on
+// each side by identical file contents, and returns the items in merged order
+// along with any items renamed differently by both sides
+func mergeItems(ba, oa, ta *archive) (items []*mergeItem, renamed []*mergeItem) {
+	renamesO, renamesT := mergeRenames(ba, oa), mergeRenames(ba, ta)
+	usedO, usedT := make(map[string]struct{}), make(map[string]struct{})
+
+	version := func(a *archive, pathname string, used map[string]struct{}) *mergeVersion {
+		if item, present := a.lookup[pathname]; present {
+			used[pathname] = struct{}{}
+			for idx, other := range a.entries {
+				if other == item {
+					return &mergeVersion{item: item, index: idx}
+				}
+			}
+		}
+		return &mergeVersion{index: -1}
+	}
+
+	var all []*mergeItem
+	for idx, be := range ba.entries {
+		pathname := be.GetPathname()
+		if pathname == "" {
+			continue
+		}
+		oPath, tPath := pathname, pathname
+		if v, ok := renamesO[pathname]; ok {
+			oPath = v
+		}
+		if v, ok := renamesT[pathname]; ok {
+			tPath = v
+		}
+		item := &mergeItem{
+			pathname: pathname,
+			base:     &mergeVersion{item: be, index: idx},
+			ours:     version(oa, oPath, usedO),
+			theirs:   version(ta, tPath, usedT),
+		}
+		switch {
+		case oPath != pathname && tPath != pathname && oPath != tPath:
+			item.pathname = oPath
+			renamed = append(renamed, item)
+		case oPath != pathname:
+			item.pathname = oPath
+		case tPath != pathname:
+			item.pathname = tPath
+		}
+		all = append(all, item)
+	}
+
+	// entries added by ours, possibly also added by theirs
+	for idx, oe := range oa.entries {
+		pathname := oe.GetPathname()
+		if _, used := usedO[pathname]; used || pathname == "" {
+			continue
+		}
+		item := &mergeItem{
+			pathname: pathname,
+			base:     &mergeVersion{index: -1},
+			ours:     &mergeVersion{item: oe, index: idx},
+			theirs:   &mergeVersion{index: -1},
+		}
+		if _, used := usedT[pathname]; !used {
+			item.theirs = version(ta, pathname, usedT)
+		}
+		all = append(all, item)
+	}
+
+	// entries only added by theirs
+	for idx, te := range ta.entries {
+		pathname := te.GetPathname()
+		if _, used := usedT[pathname]; used || pathname == "" {
+			continue
+		}
+		all = append(all, &mergeItem{
+			pathname: pathname,
+			base:     &mergeVersion{index: -1},
+			ours:     &mergeVersion{index: -1},
+			theirs:   &mergeVersion{item: te, index: idx},
+		})
+	}
+
+	// order by ours, then insert the others after their preceding entry in
+	// theirs
+	byOurs := make([]*mergeItem, len(oa.entries))
+	byTheirs := make([]*mergeItem, len(ta.entries))
+	for _, item := range all {
+		if item.ours.present() {
+			byOurs[item.ours.index] = item
+		}
+		if item.theirs.present() {
+			byTheirs[item.theirs.index] = item
+		}
+	}
+	for _, item := range byOurs {
+		if item != nil {
+			items = append(items, item)
+		}
+	}
+	for idx, item := range byTheirs {
+		if item == nil || item.ours.present() {
+			continue
+		}
+		position := 0
+		for prev := idx - 1; prev >= 0 && position == 0; prev-- {
+			for pos, other := range items {
+				if other == byTheirs[prev] {
+					position = pos + 1
+					break
+				}
+			}
+		}
+		items = append(items[:position], append([]*mergeItem{item}, items[position:]...)...)
+	}
+	return
+}
+
+// mergeRenames returns the base pathnames of files which were renamed within
+// the side archive, mapped to their new pathnames. A rename is a file removed
+// from the base with exactly one added file of the same, non-empty, contents
+func mergeRenames(ba, side *archive) (renames map[string]string) {
+	renames = make(map[string]string)
+	added := make(map[string][]string)
+	for _, item := range side.entries {
+		if pathname := item.GetPathname(); item.IsFile() && item.GetBody() != "" {
+			if _, present := ba.lookup[pathname]; !present {
+				added[item.GetBody()] = append(added[item.GetBody()], pathname)
+			}
+		}
+	}
+
+	removed := make(map[string][]string)
+	for _, item := range ba.entries {
+		if pathname := item.GetPathname(); item.IsFile() && item.GetBody() != "" {
+			if _, present := side.lookup[pathname]; !present {
+				removed[item.GetBody()] = append(removed[item.GetBody()], pathname)
+			}
+		}
+	}
+
+	for body, pathnames := range removed {
+		if len(pathnames) == 1 && len(added[body]) == 1 {
+			renames[pathnames[0]] = added[body][0]
+		}
+	}
+	return
+}
+
+// mergeLines performs a line-level three-way merge, returning false when
+// there are overlapping changes which have been marked with conflict markers
+func mergeLines(base, ours, theirs string, options *MergeOptions) (merged string, clean bool) {
+	linesB, linesO, linesT := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchO, matchT := matchLines(linesB, linesO), matchLines(linesB, linesT)
+
+	clean = true
+	var buf strings.Builder
+	i, j, k := 0, 0, 0
+	for {
+		// find the next base line unchanged within both sides
+		next := i
+		for ; next < len(linesB); next++ {
+			if matchO[next] >= j && matchT[next] >= k {
+				break
+			}
+		}
+
+		endO, endT := len(linesO), len(linesT)
+		if next < len(linesB) {
+			endO, endT = matchO[next], matchT[next]
+		}
+
+		chunkB, chunkO, chunkT := linesB[i:next], linesO[j:endO], linesT[k:endT]
+		switch {
+		case equalLines(chunkO, chunkT), equalLines(chunkB, chunkT):
+			writeLines(&buf, chunkO, false)
+		case equalLines(chunkB, chunkO):
+			writeLines(&buf, chunkT, false)
+		default:
+			clean = false
+			buf.WriteString("<<<<<<< " + options.oursLabel() + "\n")
+			writeLines(&buf, chunkO, true)
+			buf.WriteString("=======\n")
+			writeLines(&buf, chunkT, true)
+			buf.WriteString(">>>>>>> " + options.theirsLabel() + "\n")
+		}
+
+		if next >= len(linesB) {
+			break
+		}
+		buf.WriteString(linesB[next])
+		i, j, k = next+1, endO+1, endT+1
+	}
+
+	merged = buf.String()
+	return
+}
+
+// matchLines returns, for each line of a, the index of the matching line of
+// b or -1 when the line was changed
+func matchLines(a, b []string) (matches []int) {
+	matches = make([]int, len(a))
+	for idx := range matches {
+		matches[idx] = -1
+	}
+	i, j := 0, 0
+	for _, op := range diffLines(a, b) {
+		switch op.Op {
+		case ' ':
+			matches[i] = j
+			i, j = i+1, j+1
+		case '-':
+			i += 1
+		case '+':
+			j += 1
+		}
+	}
+	return
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeLines writes all lines to the buffer, when terminate is true the last
+// line has a newline added if missing
+func writeLines(buf *strings.Builder, lines []string, terminate bool) {
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	if terminate && len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		buf.WriteString("\n")
+	}
+}
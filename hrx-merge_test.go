@@ -0,0 +1,156 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	tMergeBase = `<===> one.txt
+line 1
+line 2
+line 3
+line 4
+line 5
+<===> two.txt
+unchanged
+<===> moved.txt
+moved contents
+<===> deleted.txt
+deleted
+<===> conflict.txt
+original
+`
+	tMergeOurs = `<===> one.txt
+line 1 ours
+line 2
+line 3
+line 4
+line 5
+<===> two.txt
+unchanged
+<===> renamed.txt
+moved contents
+<===> conflict.txt
+ours
+<===> ours.txt
+added by ours
+`
+	tMergeTheirs = `<=====> conflict.txt
+theirs
+<=====> one.txt
+line 1
+line 2
+line 3
+line 4
+line 5 theirs
+<=====> two.txt
+unchanged
+<=====>
+a comment
+<=====> moved.txt
+moved contents
+<=====> deleted.txt
+deleted
+<=====> theirs.txt
+added by theirs
+`
+)
+
+func TestMerge(t *testing.T) {
+	Convey("Merge", t, func() {
+		base, err := ParseData("base.hrx", tMergeBase)
+		So(err, ShouldBeNil)
+		ours, err := ParseData("ours.hrx", tMergeOurs)
+		So(err, ShouldBeNil)
+		theirs, err := ParseData("theirs.hrx", tMergeTheirs)
+		So(err, ShouldBeNil)
+
+		Convey("entry level merging", func() {
+			merged, conflicts, ee := Merge(base, ours, theirs, nil)
+			So(ee, ShouldBeNil)
+			So(merged.GetBoundary(), ShouldEqual, 3)
+			So(merged.List(), ShouldEqual, []string{"one.txt", "two.txt", "renamed.txt", "theirs.txt", "conflict.txt", "ours.txt"})
+
+			body, _, _ := merged.Get("one.txt")
+			So(body, ShouldEqual, "line 1 ours\nline 2\nline 3\nline 4\nline 5 theirs")
+			body, comment, _ := merged.Get("renamed.txt")
+			So(body, ShouldEqual, "moved contents")
+			So(comment, ShouldEqual, "a comment\n")
+
+			So(conflicts, ShouldHaveLength, 1)
+			So(conflicts[0].Pathname, ShouldEqual, "conflict.txt")
+			So(conflicts[0].Kind, ShouldEqual, ConflictContent)
+			So(conflicts[0].Line, ShouldEqual, 16)
+			So(strings.Split(merged.String(), "\n")[15], ShouldEqual, "<===> conflict.txt")
+			body, _, _ = merged.Get("conflict.txt")
+			So(body, ShouldEqual, "<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n")
+
+			b, ee := ParseData("merged.hrx", merged.String())
+			So(ee, ShouldBeNil)
+			So(b.List(), ShouldEqual, merged.List())
+		})
+
+		Convey("delete/modify and comments", func() {
+			So(theirs.Set("deleted.txt", "modified", ""), ShouldBeNil)
+			So(ours.SetComment("ours comment"), ShouldBeNil)
+			So(theirs.SetComment("theirs comment"), ShouldBeNil)
+			merged, conflicts, ee := Merge(base, ours, theirs, &MergeOptions{OursLabel: "HEAD", TheirsLabel: "branch"})
+			So(ee, ShouldBeNil)
+			var kinds []ConflictKind
+			for _, conflict := range conflicts {
+				kinds = append(kinds, conflict.Kind)
+			}
+			So(kinds, ShouldEqual, []ConflictKind{ConflictComment, ConflictDeleteModify, ConflictContent})
+			body, _, ok := merged.Get("deleted.txt")
+			So(ok, ShouldBeTrue)
+			So(body, ShouldEqual, "modified")
+			comment, _ := merged.GetComment()
+			So(comment, ShouldEqual, "ours comment")
+			body, _, _ = merged.Get("conflict.txt")
+			So(body, ShouldStartWith, "<<<<<<< HEAD\n")
+			So(body, ShouldEndWith, ">>>>>>> branch\n")
+		})
+
+		Convey("rename/rename", func() {
+			theirs.Delete("moved.txt")
+			So(theirs.Set("elsewhere.txt", "moved contents", ""), ShouldBeNil)
+			merged, conflicts, ee := Merge(base, ours, theirs, nil)
+			So(ee, ShouldBeNil)
+			So(conflicts, ShouldHaveLength, 2)
+			So(conflicts[0].Pathname, ShouldEqual, "renamed.txt")
+			So(conflicts[0].Kind, ShouldEqual, ConflictRename)
+			So(merged.Entry("renamed.txt"), ShouldNotBeNil)
+			So(merged.Entry("elsewhere.txt"), ShouldBeNil)
+		})
+
+		Convey("line merging", func() {
+			merged, clean := mergeLines("a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\nd\n", nil)
+			So(clean, ShouldBeTrue)
+			So(merged, ShouldEqual, "a\nB\nc\nd\n")
+			merged, clean = mergeLines("a\nb\nc\n", "a\nc\n", "a\nb\nc\n", nil)
+			So(clean, ShouldBeTrue)
+			So(merged, ShouldEqual, "a\nc\n")
+			merged, clean = mergeLines("", "x\n", "y", nil)
+			So(clean, ShouldBeFalse)
+			So(merged, ShouldEqual, "<<<<<<< ours\nx\n=======\ny\n>>>>>>> theirs\n")
+		})
+
+	})
+}
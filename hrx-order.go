@@ -0,0 +1,113 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+)
+
+// CompareFn is the comparison signature used to Sort archive entries,
+// returning a negative number when a sorts before b, a positive number when
+// a sorts after b and zero when the order does not matter
+type CompareFn func(a, b Entry) (order int)
+
+// LexicalOrder is a CompareFn which compares pathnames one path component at
+// a time, by byte value, keeping the contents of each directory together
+func LexicalOrder(a, b Entry) (order int) {
+	return compareComponents(a.GetPathname(), b.GetPathname(), strings.Compare)
+}
+
+// NaturalOrder is like LexicalOrder except that runs of digits are compared
+// by numeric value, for example "file2.txt" sorts before "file10.txt"
+func NaturalOrder(a, b Entry) (order int) {
+	return compareComponents(a.GetPathname(), b.GetPathname(), compareNatural)
+}
+
+// DirsFirst returns a CompareFn which sorts directories, explicit or implied
+// by the pathnames of their contents, before the files within the same
+// parent directory. Otherwise, the fn given is used to compare the entries
+func DirsFirst(fn CompareFn) CompareFn {
+	return func(a, b Entry) (order int) {
+		an, bn := pathComponents(a.GetPathname()), pathComponents(b.GetPathname())
+		for idx := 0; idx < len(an) && idx < len(bn); idx++ {
+			if an[idx] != bn[idx] {
+				aDir := idx < len(an)-1 || a.IsDir()
+				bDir := idx < len(bn)-1 || b.IsDir()
+				if aDir && !bDir {
+					return -1
+				} else if !aDir && bDir {
+					return 1
+				}
+				break
+			}
+		}
+		return fn(a, b)
+	}
+}
+
+// pathComponents splits the pathname into its path components, without any
+// trailing directory separator
+func pathComponents(pathname string) (names []string) {
+	return strings.Split(strings.TrimSuffix(pathname, "/"), "/")
+}
+
+// compareComponents compares the path components of each pathname with the
+// cmp func given, parent directories sort before their contents
+func compareComponents(a, b string, cmp func(a, b string) int) (order int) {
+	an, bn := pathComponents(a), pathComponents(b)
+	for idx := 0; idx < len(an) && idx < len(bn); idx++ {
+		if order = cmp(an[idx], bn[idx]); order != 0 {
+			return
+		}
+	}
+	return len(an) - len(bn)
+}
+
+// compareNatural compares the strings given, with runs of digits compared
+// by numeric value and equal values with fewer leading zeros sorting first
+func compareNatural(a, b string) (order int) {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			ad, bd := digitRun(a), digitRun(b)
+			at, bt := strings.TrimLeft(ad, "0"), strings.TrimLeft(bd, "0")
+			if order = len(at) - len(bt); order != 0 {
+				return
+			} else if order = strings.Compare(at, bt); order != 0 {
+				return
+			} else if order = len(ad) - len(bd); order != 0 {
+				return
+			}
+			a, b = a[len(ad):], b[len(bd):]
+			continue
+		} else if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// digitRun returns the leading run of digits within the string given
+func digitRun(s string) (digits string) {
+	end := 0
+	for end < len(s) && isDigit(s[end]) {
+		end += 1
+	}
+	return s[:end]
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"io"
+	"strings"
+)
+
+var _ io.Reader = (*Reader)(nil)
+
+// Header describes a single file or directory entry of an HRX archive
+type Header struct {
+	// Pathname is the pathname from the HRX boundary line, directories end
+	// with a trailing slash
+	Pathname string
+	// Comment is the optional comment preceding this entry
+	Comment string
+	// Line is the line number of the boundary line for this entry
+	Line int
+}
+
+// IsDir reports true when the Pathname ends with a directory separator
+func (h *Header) IsDir() bool {
+	return strings.HasSuffix(h.Pathname, "/")
+}
+
+// IsFile reports true when the Pathname does not end with a directory
+// separator
+func (h *Header) IsFile() bool {
+	return h.Pathname != "" && !h.IsDir()
+}
+
+// IsHRX reports true when the Pathname ends with a `.hrx` extension
+func (h *Header) IsHRX() bool {
+	return strings.HasSuffix(h.Pathname, ".hrx")
+}
+
+// Reader provides sequential access to the entries of an HRX archive without
+// buffering the whole archive in memory. Reader is modeled after the
+// archive/tar.Reader, Next advances to the next entry and Read returns the
+// body contents of the current entry
+//
+// Reader validates the archive incrementally and returns the same *Error
+// values as ParseReader. The only state retained between entries is the set
+// of pathnames already seen, for detecting ErrDuplicatePath and
+// ErrFileAsParentDir conditions
+type Reader struct {
+	filename string
+	boundary int
+
+	s       *Scanner
+	started bool
+	next    *readerHeader
+
+	header *Header
+	body   bool
+	buffer string
+	size   int
+
+	comment *string
+	paths   *pathTracker
+	err     error
+}
+
+type readerHeader struct {
+	line     int
+	pathname string
+	src      *headerSource
+	err      error
+}
+
+// NewReader constructs a new Reader instance, associating the filename with
+// any errors returned
+func NewReader(filename string, reader io.Reader) *Reader {
+	return &Reader{
+		filename: filename,
+		s:        NewScanner(reader),
+		paths:    newPathTracker(),
+	}
+}
+
+// Boundary returns the boundary size of the archive, which is only known
+// after the first call to Next
+func (r *Reader) Boundary() (size int) {
+	return r.boundary
+}
+
+// Comment returns the archive-level comment, which is only known after Next
+// returns io.EOF
+func (r *Reader) Comment() (comment string, ok bool) {
+	if ok = r.comment != nil; ok {
+		comment = *r.comment
+	}
+	return
+}
+
+// Next advances to the next file or directory entry within the archive,
+// skipping any unread body content of the current entry. Comments preceding
+// an entry are returned as part of the Header. Next returns io.EOF at the end
+// of the archive
+func (r *Reader) Next() (hdr *Header, err error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// skip any remaining body content
+	for r.body {
+		if _, r.err = r.readBodyLine(); r.err != nil {
+			return nil, r.err
+		}
+	}
+	r.header, r.buffer, r.size = nil, "", 0
+
+	if !r.started {
+		if r.err = r.start(); r.err != nil {
+			return nil, r.err
+		}
+	}
+
+	var next *readerHeader
+	if next, r.next = r.next, nil; next == nil {
+		return nil, io.EOF
+	} else if r.err = r.checkHeader(next); r.err != nil {
+		return nil, r.err
+	}
+
+	var comment string
+	if next.pathname == "" {
+		// comments are buffered as they belong to the next entry
+		comment = r.readComment()
+		if next, r.next = r.next, nil; next == nil {
+			// last item is a comment, assign to archive level
+			r.comment = &comment
+			return nil, io.EOF
+		} else if next.pathname == "" {
+			r.err = next.src.error(r.filename, next.line, 1, nil, ErrSequentialComments)
+			return nil, r.err
+		} else if r.err = r.checkHeader(next); r.err != nil {
+			return nil, r.err
+		}
+	}
+
+	if r.err = r.checkPathname(next); r.err != nil {
+		return nil, r.err
+	}
+
+	r.header = &Header{
+		Pathname: next.pathname,
+		Comment:  comment,
+		Line:     next.line,
+	}
+	r.body = true
+	hdr = r.header
+	return
+}
+
+// Read reads the body content of the current entry, returning io.EOF when the
+// end of the body is reached. Directories always return io.EOF
+func (r *Reader) Read(p []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	} else if r.header == nil || r.header.IsDir() {
+		return 0, io.EOF
+	}
+
+	for n < len(p) {
+		if r.buffer == "" {
+			if !r.body {
+				break
+			} else if r.buffer, r.err = r.readBodyLine(); r.err != nil {
+				return n, r.err
+			}
+			continue
+		}
+		copied := copy(p[n:], r.buffer)
+		r.buffer = r.buffer[copied:]
+		n += copied
+	}
+
+	if n == 0 && len(p) > 0 {
+		err = io.EOF
+	}
+	return
+}
+
+func (r *Reader) start() (err error) {
+	r.started = true
+	if !r.s.Scan() {
+		return newError(r.filename, 0, ErrEmptyArchive, ErrMalformedInput)
+	}
+	content, line, boundary, pathname, header, sErr := r.s.Get()
+	src := r.source(content, line, boundary)
+	if content == "" {
+		return newError(r.filename, 0, ErrEmptyArchive, ErrMalformedInput)
+	} else if !header {
+		// this is not a valid .hrx file
+		return src.error(r.filename, line, 1, ErrBadArchiveHeader, ErrMalformedInput)
+	} else if boundary == 0 {
+		return src.error(r.filename, line, 2, ErrBadBoundary, ErrMalformedInput)
+	}
+	r.boundary = boundary
+	r.next = &readerHeader{line: line, pathname: pathname, src: src, err: sErr}
+	return
+}
+
+// readLine scans the next line of the current entry body, stopping at the
+// next boundary line of this archive
+func (r *Reader) readLine() (content string, ok bool) {
+	if !r.s.Scan() {
+		return
+	}
+	var line, boundary int
+	var pathname string
+	var header bool
+	var sErr error
+	if content, line, boundary, pathname, header, sErr = r.s.Get(); content == "" {
+		// end of input
+		return
+	} else if header && boundary == r.boundary {
+		r.next = &readerHeader{line: line, pathname: pathname, src: r.source(content, line, boundary), err: sErr}
+		return
+	}
+	ok = true
+	return
+}
+
+// source returns the headerSource of the current line
+func (r *Reader) source(content string, line, boundary int) (src *headerSource) {
+	offset, column := r.s.Position()
+	return &headerSource{line: line, offset: offset, text: strings.TrimSuffix(content, "\n"), boundary: boundary, column: column}
+}
+
+func (r *Reader) readBodyLine() (content string, err error) {
+	var ok bool
+	if content, ok = r.readLine(); !ok {
+		r.body = false
+		content = ""
+		return
+	}
+
+	if r.header.IsDir() {
+		// directories may be followed by a single empty line
+		if r.size += len(content); r.size > 1 {
+			r.body = false
+			err = newError(r.filename, r.header.Line, nil, ErrDirectoryHasContents)
+		}
+		return
+	}
+
+	if _, nextBoundary, _, nextIsHeader, _, ok := r.s.Peek(); ok && nextIsHeader && nextBoundary == r.boundary {
+		// last newline is a part of the next boundary
+		content = strings.TrimSuffix(content, "\n")
+	}
+	return
+}
+
+func (r *Reader) readComment() (comment string) {
+	for {
+		content, ok := r.readLine()
+		if !ok {
+			return
+		}
+		comment += content
+	}
+}
+
+func (r *Reader) checkHeader(next *readerHeader) (err error) {
+	if next.err != nil {
+		return next.src.error(r.filename, next.line, next.src.column, next.err, ErrMalformedInput)
+	} else if idx, ee := checkPathComponentsAt(next.pathname); ee != nil {
+		return next.src.error(r.filename, next.line, next.src.pathColumn(idx), ee, ErrBadFileEntry)
+	}
+	return
+}
+
+// checkPathname validates the pathname against all previously seen pathnames
+func (r *Reader) checkPathname(next *readerHeader) (err error) {
+	if ee := r.paths.add(next.pathname); ee != nil {
+		return next.src.error(r.filename, next.line, next.src.pathColumn(0), nil, ee)
+	}
+	return
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// readAll reads all entries from the given Reader into a new Archive
+func readAll(filename, contents string) (a *archive, err error) {
+	r := NewReader(filename, strings.NewReader(contents))
+	a = newArchive(filename, "")
+	for {
+		var hdr *Header
+		if hdr, err = r.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+				a.boundary = r.Boundary()
+				if comment, ok := r.Comment(); ok {
+					a.comment = &comment
+				}
+			}
+			return
+		}
+		var body []byte
+		if body, err = io.ReadAll(r); err != nil {
+			return
+		}
+		item := newEntry(hdr.Line, r.Boundary(), hdr.Pathname, string(body), nil)
+		if hdr.Comment != "" {
+			item.comment = &hdr.Comment
+		}
+		a.entries = append(a.entries, item)
+		a.lookup[hdr.Pathname] = item
+	}
+}
+
+func TestReader(t *testing.T) {
+	Convey("Streaming Reader", t, func() {
+
+		Convey("HRX Specification", func() {
+			for _, hrxname := range gTestDataFiles {
+				contents := TD.F(hrxname)
+				expected, expectedErr := ParseData(hrxname, contents)
+				a, err := readAll(hrxname, contents)
+				if expectedErr != nil {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, expectedErr.Error())
+					continue
+				}
+				So(err, ShouldBeNil)
+				So(a.List(), ShouldEqual, expected.List())
+				So(a.GetBoundary(), ShouldEqual, expected.GetBoundary())
+				expectedComment, _ := expected.GetComment()
+				actualComment, _ := a.GetComment()
+				So(actualComment, ShouldEqual, expectedComment)
+				for _, pathname := range expected.List() {
+					expectedBody, expectedEntryComment, _ := expected.Get(pathname)
+					actualBody, actualEntryComment, _ := a.Get(pathname)
+					if !strings.HasSuffix(pathname, "/") {
+						So(actualBody, ShouldEqual, expectedBody)
+					}
+					So(actualEntryComment, ShouldEqual, expectedEntryComment)
+					if strings.HasSuffix(pathname, ".hrx") {
+						body, _, _ := expected.Get(pathname)
+						_, ee := ParseData(pathname, body)
+						_, re := readAll(pathname, body)
+						if ee != nil {
+							So(re, ShouldNotBeNil)
+							So(re.Error(), ShouldEqual, ee.Error())
+						} else {
+							So(re, ShouldBeNil)
+						}
+					}
+				}
+			}
+		})
+
+		Convey("partial reads", func() {
+			r := NewReader("testing.hrx", strings.NewReader(tArchiveFsHRX))
+			hdr, err := r.Next()
+			So(err, ShouldBeNil)
+			So(hdr.Pathname, ShouldEqual, "file.txt")
+			So(hdr.Comment, ShouldEqual, "file comment\n")
+			So(hdr.Line, ShouldEqual, 3)
+			So(hdr.IsFile(), ShouldBeTrue)
+			buf := make([]byte, 5)
+			n, err := r.Read(buf)
+			So(err, ShouldBeNil)
+			So(string(buf[:n]), ShouldEqual, "hello")
+			hdr, err = r.Next()
+			So(err, ShouldBeNil)
+			So(hdr.Pathname, ShouldEqual, "dir/file.txt")
+			hdr, err = r.Next()
+			So(err, ShouldBeNil)
+			hdr, err = r.Next()
+			So(err, ShouldBeNil)
+			So(hdr.IsDir(), ShouldBeTrue)
+			n, err = r.Read(buf)
+			So(n, ShouldEqual, 0)
+			So(err, ShouldEqual, io.EOF)
+			hdr, err = r.Next()
+			So(err, ShouldBeNil)
+			So(hdr.IsHRX(), ShouldBeTrue)
+			hdr, err = r.Next()
+			So(err, ShouldEqual, io.EOF)
+			So(hdr, ShouldBeNil)
+			_, ok := r.Comment()
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("incremental errors", func() {
+			r := NewReader("testing.hrx", strings.NewReader("<==> dir/file\n<==> other\n<==> dir\n"))
+			_, err := r.Next()
+			So(err, ShouldBeNil)
+			_, err = r.Next()
+			So(err, ShouldBeNil)
+			_, err = r.Next()
+			So(errors.Is(err, ErrFileAsParentDir), ShouldBeTrue)
+			_, err = r.Next()
+			So(errors.Is(err, ErrFileAsParentDir), ShouldBeTrue)
+
+			r = NewReader("testing.hrx", strings.NewReader("<==> dir/file\n<==> dir/\n<==> dir/file\n"))
+			_, err = r.Next()
+			So(err, ShouldBeNil)
+			_, err = r.Next()
+			So(err, ShouldBeNil)
+			_, err = r.Next()
+			So(errors.Is(err, ErrDuplicatePath), ShouldBeTrue)
+
+			r = NewReader("testing.hrx", strings.NewReader(""))
+			_, err = r.Next()
+			So(errors.Is(err, ErrEmptyArchive), ShouldBeTrue)
+		})
+
+	})
+}
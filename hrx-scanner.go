@@ -20,15 +20,22 @@ import (
 	"sync"
 
 	"github.com/go-corelibs/scanners"
-	clSlices "github.com/go-corelibs/slices"
 )
 
 // Scanner is a line-reading text scanner for parsing HRX archive contents
 type Scanner struct {
 	scanners.LineScanner
 
-	src   string
-	lines *clSlices.Stack[*scannedLine]
+	src    string
+	line   int
+	offset int
+	last   *scannedLine
+
+	// boundary is the archive boundary, taken from the first header line
+	boundary int
+	// comment is true while the lines following a comment boundary are
+	// being scanned
+	comment bool
 
 	m *sync.RWMutex
 }
@@ -38,14 +45,20 @@ type scannedLine struct {
 	boundary int
 	pathname string
 	content  string
+	offset   int
+	column   int
 	err      error
+	// archive is true for the boundary lines of the archive, as opposed to
+	// boundary-like lines within bodies
+	archive bool
+	// comment is true for the lines of a comment
+	comment bool
 }
 
 // NewScanner constructs a new Scanner instance
 func NewScanner(reader io.Reader) *Scanner {
 	return &Scanner{
 		LineScanner: *scanners.NewLineScanner(reader),
-		lines:       clSlices.NewStack[*scannedLine](),
 		m:           &sync.RWMutex{},
 	}
 }
@@ -61,9 +74,20 @@ func (s *Scanner) Scan() (ok bool) {
 	}
 
 	content := s.LineScanner.Text() // includes trailing newline
-	item := &scannedLine{content: content}
-	item.boundary, item.pathname, item.header, item.err = s.parseHeaderLine(content)
-	s.lines.Push(item)
+	item := &scannedLine{content: content, offset: s.offset}
+	item.boundary, item.pathname, item.header, item.column, item.err = s.parseHeaderLine(content)
+	s.offset += len(content)
+	if item.header && item.boundary > 0 && (s.boundary == 0 || item.boundary == s.boundary) {
+		item.archive = true
+		s.boundary = item.boundary
+		s.comment = item.pathname == "" && item.err == nil
+	} else {
+		item.comment = s.comment
+	}
+	// only the last line is retained, keeping memory use bounded by the
+	// length of the longest line rather than the size of the input
+	s.line += 1
+	s.last = item
 	return
 }
 
@@ -71,12 +95,14 @@ func (s *Scanner) Peek() (content string, boundary int, pathname string, header
 	s.m.Lock()
 	defer s.m.Unlock()
 	if content, ok = s.LineScanner.Peek(); ok {
-		boundary, pathname, header, err = s.parseHeaderLine(content)
+		boundary, pathname, header, _, err = s.parseHeaderLine(content)
 	}
 	return
 }
 
-func (s *Scanner) parseHeaderLine(content string) (boundary int, pathname string, header bool, err error) {
+// parseHeaderLine parses the content as a boundary line, the column returned
+// is the position of the character responsible for any error
+func (s *Scanner) parseHeaderLine(content string) (boundary int, pathname string, header bool, column int, err error) {
 	if strings.HasSuffix(content, "\n") {
 		content = strings.TrimSuffix(content, "\n")
 	}
@@ -88,6 +114,7 @@ func (s *Scanner) parseHeaderLine(content string) (boundary int, pathname string
 			if header {
 				// boundary + space present, pathname remainder
 				if err = checkPathCharacter(r); err != nil {
+					column = i + 1
 					return
 				}
 				pathname += string(r)
@@ -103,6 +130,7 @@ func (s *Scanner) parseHeaderLine(content string) (boundary int, pathname string
 						continue
 					} else {
 						// this is a malformed header
+						column = next + 1
 						err = ErrNoSpaceBeforePath
 						return
 					}
@@ -127,9 +155,9 @@ func (s *Scanner) parseHeaderLine(content string) (boundary int, pathname string
 func (s *Scanner) Get() (content string, line, boundary int, pathname string, header bool, err error) {
 	s.m.RLock()
 	defer s.m.RUnlock()
-	if last, ok := s.lines.Last(); ok {
+	if last := s.last; last != nil {
 		content = last.content
-		line = s.lines.Len()
+		line = s.line
 		boundary = last.boundary
 		pathname = last.pathname
 		header = last.header
@@ -137,3 +165,16 @@ func (s *Scanner) Get() (content string, line, boundary int, pathname string, he
 	}
 	return
 }
+
+// Position returns the byte offset of the start of the current line within
+// the input and, when Get returns an error, the column of the character
+// responsible for the error
+func (s *Scanner) Position() (offset, column int) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	if last := s.last; last != nil {
+		offset = last.offset
+		column = last.column
+	}
+	return
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+)
+
+// SyntaxKind identifies the kind of each SyntaxNode
+type SyntaxKind string
+
+const (
+	// SyntaxBoundary is a boundary line without a pathname, preceding a
+	// comment
+	SyntaxBoundary SyntaxKind = "boundary"
+	// SyntaxComment is the contents of a comment
+	SyntaxComment SyntaxKind = "comment"
+	// SyntaxHeader is a boundary line with a pathname, starting an entry
+	SyntaxHeader SyntaxKind = "header"
+	// SyntaxBody is the contents of a file, or nested archive, entry
+	SyntaxBody SyntaxKind = "body"
+	// SyntaxSeparator is the newline between an entry body and the next
+	// boundary line, which is not a part of the body
+	SyntaxSeparator SyntaxKind = "separator"
+)
+
+// SyntaxNode is a single piece of the concrete syntax of an Archive
+type SyntaxNode struct {
+	// Kind is the kind of syntax
+	Kind SyntaxKind
+	// Pathname is the entry this node belongs to, empty for the archive
+	// comment
+	Pathname string
+	// Offset is the byte offset of this node within the archive contents
+	Offset int
+	// Line is the line number this node starts on
+	Line int
+	// Text is the exact contents of this node
+	Text string
+}
+
+// End returns the byte offset immediately following this node
+func (n SyntaxNode) End() (offset int) {
+	return n.Offset + len(n.Text)
+}
+
+func (a *archive) Syntax() (nodes []SyntaxNode) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	nodes, _ = a.syntax()
+	return
+}
+
+// syntax returns the concrete syntax of this archive along with the number
+// of lines, this is the one place the layout of an archive is decided
+func (a *archive) syntax() (nodes []SyntaxNode, lines int) {
+	offset, lines := 0, 1
+	add := func(kind SyntaxKind, pathname, text string) {
+		if n := len(nodes) - 1; n >= 0 && !strings.HasSuffix(nodes[n].Text, "\n") && text != "" {
+			// only the last line of a parsed archive may be missing a newline
+			if nodes[n].Kind == SyntaxHeader || nodes[n].Kind == SyntaxBoundary {
+				nodes[n].Text += "\n"
+				offset += 1
+				lines += 1
+			}
+		}
+		if text != "" {
+			nodes = append(nodes, SyntaxNode{Kind: kind, Pathname: pathname, Offset: offset, Line: lines, Text: text})
+			offset += len(text)
+			lines += strings.Count(text, "\n")
+		}
+	}
+
+	last := len(a.entries) - 1
+	for idx, item := range a.entries {
+		pathname := item.GetPathname()
+		if item.comment != nil {
+			add(SyntaxBoundary, pathname, boundaryLine(item.commentHeader, item.boundary, ""))
+			if comment := *item.comment; comment != "" && !strings.HasSuffix(comment, "\n") {
+				add(SyntaxComment, pathname, comment+"\n")
+			} else {
+				add(SyntaxComment, pathname, comment)
+			}
+		}
+		add(SyntaxHeader, pathname, boundaryLine(item.header, item.boundary, pathname))
+		add(SyntaxBody, pathname, item.GetBody())
+		if idx < last || a.comment != nil {
+			if item.IsFile() && (item.GetBody() != "" || item.sep) {
+				add(SyntaxSeparator, pathname, "\n")
+			}
+		}
+	}
+
+	if a.comment != nil {
+		add(SyntaxBoundary, "", boundaryLine(a.commentHeader, a.boundary, ""))
+		add(SyntaxComment, "", *a.comment)
+	}
+	return
+}
+
+// boundaryLine returns the boundary line as parsed when it still has the size
+// and pathname given, keeping a missing final newline or the space following
+// a comment boundary, otherwise a new boundary line is returned
+func boundaryLine(parsed string, size int, pathname string) (line string) {
+	line = newBoundary(size, pathname)
+	expected, text := strings.TrimSuffix(line, "\n"), strings.TrimSuffix(parsed, "\n")
+	if parsed != "" && (text == expected || (pathname == "" && text == expected+" ")) {
+		line = parsed
+	}
+	return
+}
+
+// render returns the concatenated text of all syntax nodes
+func (a *archive) render() (data string) {
+	nodes, _ := a.syntax()
+	var buf strings.Builder
+	for _, node := range nodes {
+		buf.WriteString(node.Text)
+	}
+	return buf.String()
+}
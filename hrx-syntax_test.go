@@ -0,0 +1,119 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// tSyntaxHRX has the whitespace details of a hand-edited archive
+const tSyntaxHRX = `<===> empty.txt
+
+<===> blank.txt
+<===>
+<===> dir/
+
+<===>
+a comment
+<===> body.txt
+body
+
+<===> nested.hrx
+<====> inner.txt
+
+<====> last.txt
+last
+<===> last.txt
+last
+<===>
+archive comment`
+
+func TestSyntax(t *testing.T) {
+	Convey("Concrete syntax", t, func() {
+		a, err := ParseData("syntax.hrx", tSyntaxHRX)
+		So(err, ShouldBeNil)
+
+		Convey("lossless round trip", func() {
+			So(a.String(), ShouldEqual, tSyntaxHRX)
+			body, _, _ := a.Get("empty.txt")
+			So(body, ShouldEqual, "")
+			body, _, _ = a.Get("body.txt")
+			So(body, ShouldEqual, "body\n")
+			for _, data := range []string{
+				"<===>\n<===> a\nx", "<===>\n\n<===> a\n", "<===> a\n<===>\n",
+				"<===> f\nx\n<===>", "<===> f", "<===> f\n<===> g", "<===> \ncomment\n<===> f\n", "<===> f\n<===> ",
+			} {
+				b, ee := ParseData("empty-comment.hrx", data)
+				So(ee, ShouldBeNil)
+				So(b.String(), ShouldEqual, data)
+			}
+		})
+
+		Convey("parsed boundary lines", func() {
+			b, ee := ParseData("parsed.hrx", "<===> \ncomment\n<===> f")
+			So(ee, ShouldBeNil)
+			So(b.Set("g", "", ""), ShouldBeNil)
+			So(b.String(), ShouldEqual, "<===> \ncomment\n<===> f\n<===> g\n")
+			So(b.SetBoundary(4), ShouldBeNil)
+			So(b.String(), ShouldEqual, "<====>\ncomment\n<====> f\n<====> g\n")
+
+			b, ee = ParseData("parsed.hrx", "<===> f\nx\n<===>")
+			So(ee, ShouldBeNil)
+			So(b.Set("f", "y", ""), ShouldBeNil)
+			So(b.String(), ShouldEqual, "<===> f\ny\n<===>")
+			So(b.SetComment("archive comment"), ShouldBeNil)
+			So(b.String(), ShouldEqual, "<===> f\ny\n<===>\narchive comment")
+		})
+
+		Convey("syntax nodes", func() {
+			nodes := a.Syntax()
+			var buf strings.Builder
+			for _, node := range nodes {
+				So(node.Offset, ShouldEqual, buf.Len())
+				So(node.Line, ShouldEqual, strings.Count(buf.String(), "\n")+1)
+				buf.WriteString(node.Text)
+			}
+			So(buf.String(), ShouldEqual, tSyntaxHRX)
+			So(nodes[0], ShouldResemble, SyntaxNode{Kind: SyntaxHeader, Pathname: "empty.txt", Offset: 0, Line: 1, Text: "<===> empty.txt\n"})
+			So(nodes[1], ShouldResemble, SyntaxNode{Kind: SyntaxSeparator, Pathname: "empty.txt", Offset: 16, Line: 2, Text: "\n"})
+			So(nodes[2].End(), ShouldEqual, 33)
+			last := nodes[len(nodes)-1]
+			So(last.Kind, ShouldEqual, SyntaxComment)
+			So(last.Pathname, ShouldEqual, "")
+			So(last.End(), ShouldEqual, len(tSyntaxHRX))
+		})
+
+		Convey("edits only change the affected region", func() {
+			So(a.Set("body.txt", "changed\n", "a comment\n"), ShouldBeNil)
+			So(a.String(), ShouldEqual, strings.Replace(tSyntaxHRX, "body\n\n", "changed\n\n", 1))
+
+			a.Delete("blank.txt")
+			So(a.String(), ShouldEqual, strings.Replace(
+				strings.Replace(tSyntaxHRX, "body\n\n", "changed\n\n", 1),
+				"<===> blank.txt\n<===>\n<===> dir/", "<===>\n<===> dir/", 1,
+			))
+
+			So(a.Set("nested.hrx//last.txt", "LAST\n", ""), ShouldBeNil)
+			So(a.String(), ShouldContainSubstring, "<====> inner.txt\n\n<====> last.txt\nLAST\n")
+
+			reparsed, err := ParseData("reparsed.hrx", a.String())
+			So(err, ShouldBeNil)
+			So(reparsed.String(), ShouldEqual, a.String())
+		})
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenKind identifies the kind of each Token
+type TokenKind string
+
+const (
+	// TokenBoundaryOpen is the "<" starting a boundary line
+	TokenBoundaryOpen TokenKind = "boundary-open"
+	// TokenBoundaryEquals is the run of equal signs of a boundary line
+	TokenBoundaryEquals TokenKind = "boundary-equals"
+	// TokenBoundaryClose is the ">" ending the boundary of a boundary line
+	TokenBoundaryClose TokenKind = "boundary-close"
+	// TokenPathSeparatorSpace is the space between the boundary and the
+	// pathname of a header line
+	TokenPathSeparatorSpace TokenKind = "path-separator-space"
+	// TokenPathname is the pathname of a header line
+	TokenPathname TokenKind = "pathname"
+	// TokenBodyLine is the contents of a line of an entry body, or of a
+	// line preceding the first boundary line
+	TokenBodyLine TokenKind = "body-line"
+	// TokenCommentLine is the contents of a line of a comment
+	TokenCommentLine TokenKind = "comment-line"
+	// TokenNewline is the newline ending each line
+	TokenNewline TokenKind = "newline"
+)
+
+// Token is a lexical token of an archive, the Text of all tokens
+// concatenated is exactly the input scanned
+type Token struct {
+	// Kind is the kind of token
+	Kind TokenKind
+	// Text is the exact contents of this token
+	Text string
+	// Start is the byte offset of this token within the input
+	Start int
+	// End is the byte offset immediately following this token
+	End int
+	// Line is the line number this token is on
+	Line int
+	// Column is the column number, counted in runes starting from one, of
+	// the start of this token
+	Column int
+	// Err is any error found with a TokenPathname, such as
+	// ErrContainsColon or ErrNoSpaceBeforePath
+	Err error
+}
+
+// Tokenize returns all the tokens of the data given, see Scanner.Tokens
+func Tokenize[V string | []byte | []rune](data V) (tokens []Token) {
+	for s := NewScanner(strings.NewReader(string(data))); s.Scan(); {
+		tokens = append(tokens, s.Tokens()...)
+	}
+	return
+}
+
+// Tokens returns the tokens of the current Scan line. The boundary of the
+// archive is taken from the first header line and only the boundary lines of
+// that size are tokenized as headers, all other lines are a TokenBodyLine or
+// a TokenCommentLine, depending on the preceding boundary line, followed by a
+// TokenNewline. Empty lines have only the TokenNewline
+func (s *Scanner) Tokens() (tokens []Token) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	if last := s.last; last != nil {
+		tokens = last.tokens(s.line)
+	}
+	return
+}
+
+// tokens returns the tokens of this line, which is the line number given
+func (l *scannedLine) tokens(line int) (tokens []Token) {
+	content := l.content
+	text := strings.TrimSuffix(content, "\n")
+	add := func(kind TokenKind, start, end int, err error) {
+		if end > start {
+			tokens = append(tokens, Token{
+				Kind:   kind,
+				Text:   content[start:end],
+				Start:  l.offset + start,
+				End:    l.offset + end,
+				Line:   line,
+				Column: utf8.RuneCountInString(content[:start]) + 1,
+				Err:    err,
+			})
+		}
+	}
+
+	switch {
+	case l.archive:
+		// boundaries are ascii, the close is one past the equal signs
+		closing := l.boundary + 1
+		add(TokenBoundaryOpen, 0, 1, nil)
+		add(TokenBoundaryEquals, 1, closing, nil)
+		add(TokenBoundaryClose, closing, closing+1, nil)
+		start := closing + 1
+		if start < len(text) && text[start] == ' ' {
+			add(TokenPathSeparatorSpace, start, start+1, nil)
+			start += 1
+		}
+		// the pathname is the remainder of the line, even when it is not
+		// valid and the Scanner stopped short of the end
+		add(TokenPathname, start, len(text), l.err)
+	case l.comment:
+		add(TokenCommentLine, 0, len(text), nil)
+	default:
+		add(TokenBodyLine, 0, len(text), nil)
+	}
+	add(TokenNewline, len(text), len(content), nil)
+	return
+}
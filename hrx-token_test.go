@@ -0,0 +1,90 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTokens(t *testing.T) {
+	Convey("Tokens", t, func() {
+
+		Convey("lossless byte ranges", func() {
+			for _, data := range []string{tSyntaxHRX, "", "\n", "preamble\n<=> a.txt\nbody"} {
+				var joined string
+				for _, token := range Tokenize(data) {
+					So(data[token.Start:token.End], ShouldEqual, token.Text)
+					joined += token.Text
+				}
+				So(joined, ShouldEqual, data)
+			}
+		})
+
+		Convey("header and body lines", func() {
+			tokens := Tokenize("<==> dir/fé.txt\nbody\n\n")
+			var kinds []TokenKind
+			for _, token := range tokens {
+				kinds = append(kinds, token.Kind)
+			}
+			So(kinds, ShouldEqual, []TokenKind{
+				TokenBoundaryOpen, TokenBoundaryEquals, TokenBoundaryClose,
+				TokenPathSeparatorSpace, TokenPathname, TokenNewline,
+				TokenBodyLine, TokenNewline,
+				TokenNewline,
+			})
+			So(tokens[1], ShouldResemble, Token{Kind: TokenBoundaryEquals, Text: "==", Start: 1, End: 3, Line: 1, Column: 2})
+			So(tokens[4], ShouldResemble, Token{Kind: TokenPathname, Text: "dir/fé.txt", Start: 5, End: 16, Line: 1, Column: 6})
+			So(tokens[5], ShouldResemble, Token{Kind: TokenNewline, Text: "\n", Start: 16, End: 17, Line: 1, Column: 16})
+			So(tokens[6], ShouldResemble, Token{Kind: TokenBodyLine, Text: "body", Start: 17, End: 21, Line: 2, Column: 1})
+			So(tokens[8].Line, ShouldEqual, 3)
+		})
+
+		Convey("comment lines", func() {
+			tokens := Tokenize("<=>\none\n<=> a.txt\ntwo")
+			So(tokens[4], ShouldResemble, Token{Kind: TokenCommentLine, Text: "one", Start: 4, End: 7, Line: 2, Column: 1})
+			So(tokens[len(tokens)-1], ShouldResemble, Token{Kind: TokenBodyLine, Text: "two", Start: 18, End: 21, Line: 4, Column: 1})
+		})
+
+		Convey("boundary-like lines within bodies", func() {
+			tokens := Tokenize("<===> a.hrx\n<=> inner.txt\n<=>\n")
+			So(tokens[6].Kind, ShouldEqual, TokenBodyLine)
+			So(tokens[6].Text, ShouldEqual, "<=> inner.txt")
+			So(tokens[8].Kind, ShouldEqual, TokenBodyLine)
+			So(tokens[8].Text, ShouldEqual, "<=>")
+		})
+
+		Convey("malformed headers", func() {
+			tokens := Tokenize("<=>a.txt\n")
+			So(tokens[3].Kind, ShouldEqual, TokenPathname)
+			So(tokens[3].Text, ShouldEqual, "a.txt")
+			So(tokens[3].Err, ShouldEqual, ErrNoSpaceBeforePath)
+
+			tokens = Tokenize("<=> a:b.txt\n")
+			So(tokens[4].Text, ShouldEqual, "a:b.txt")
+			So(tokens[4].Err, ShouldEqual, ErrContainsColon)
+		})
+
+		Convey("scanner tokens", func() {
+			s := NewScanner(strings.NewReader("<=> a.txt\n"))
+			So(s.Tokens(), ShouldBeEmpty)
+			So(s.Scan(), ShouldBeTrue)
+			So(s.Tokens(), ShouldHaveLength, 6)
+		})
+
+	})
+}
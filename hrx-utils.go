@@ -15,6 +15,7 @@
 package hrx
 
 import (
+	"path"
 	"strings"
 )
 
@@ -29,20 +30,69 @@ func newBoundary(size int, pathname string) (boundary string) {
 	return
 }
 
+// lineBoundary returns the size of the boundary the given line starts with,
+// if the line starts with a boundary
+func lineBoundary(line string) (size int, ok bool) {
+	if strings.HasPrefix(line, "<") {
+		for idx := 1; idx < len(line); idx++ {
+			switch line[idx] {
+			case '=':
+				size += 1
+			case '>':
+				ok = true
+				return
+			default:
+				return 0, false
+			}
+		}
+	}
+	return 0, false
+}
+
+// hasBoundaryLine reports if any line of the text given starts with a boundary
+// of the size given, which would be mistaken for an entry header
+func hasBoundaryLine(text string, size int) (found bool) {
+	for _, line := range strings.Split(text, "\n") {
+		if v, ok := lineBoundary(line); ok && v == size {
+			return true
+		}
+	}
+	return
+}
+
 func checkPathComponents(pathname string) (err error) {
+	_, err = checkPathComponentsAt(pathname)
+	return
+}
+
+// checkPathComponentsAt is like checkPathComponents and also returns the byte
+// index within the pathname of the problem found
+func checkPathComponentsAt(pathname string) (index int, err error) {
 	if pathname == "" {
 		return
 	} else if pathname[0] == '/' {
-		return ErrStartsWithDirSep
-	} else if strings.Contains(pathname, "//") {
-		return ErrContainsRelPath
+		return 0, ErrStartsWithDirSep
+	} else if idx := strings.Index(pathname, "//"); idx >= 0 {
+		return idx + 1, ErrContainsRelPath
 	}
 	for _, name := range strings.Split(pathname, "/") {
 		switch name {
 		case ".", "..":
-			return ErrContainsRelPath
+			return index, ErrContainsRelPath
+		}
+		index += len(name) + 1
+	}
+	return 0, nil
+}
+
+// checkPathname validates all characters and components of the pathname
+func checkPathname(pathname string) (err error) {
+	for _, r := range pathname {
+		if err = checkPathCharacter(r); err != nil {
+			return
 		}
 	}
+	err = checkPathComponents(pathname)
 	return
 }
 
@@ -69,3 +119,78 @@ func checkPathCharacter(r rune) (err error) {
 
 	return
 }
+
+// pathTracker records the pathnames seen for detecting ErrDuplicatePath and
+// ErrFileAsParentDir conditions without retaining any entry contents
+type pathTracker struct {
+	paths   map[string]struct{}
+	implied map[string]struct{}
+}
+
+func newPathTracker() *pathTracker {
+	return &pathTracker{
+		paths:   make(map[string]struct{}),
+		implied: make(map[string]struct{}),
+	}
+}
+
+// add validates the pathname given against all previously added pathnames
+// and if valid, tracks the pathname and all of its implied parents
+func (t *pathTracker) add(pathname string) (err error) {
+	if _, present := t.paths[pathname]; present {
+		return ErrDuplicatePath
+	}
+
+	trimmed := strings.TrimSuffix(pathname, "/")
+	if trimmed == pathname {
+		// a file cannot take the place of an existing directory
+		if _, present := t.implied[trimmed]; present {
+			return ErrFileAsParentDir
+		} else if _, present = t.paths[trimmed+"/"]; present {
+			return ErrFileAsParentDir
+		}
+	}
+
+	var parents []string
+	var build string
+	for _, name := range strings.Split(trimmed, "/") {
+		if build != "" {
+			build += "/"
+		}
+		build += name
+		if _, present := t.paths[build]; present {
+			// files never have a trailing slash
+			return ErrFileAsParentDir
+		}
+		parents = append(parents, build)
+	}
+
+	for _, parent := range parents[:len(parents)-1] {
+		t.implied[parent] = struct{}{}
+	}
+	t.paths[pathname] = struct{}{}
+	return
+}
+
+// parentDirs returns the parent directories of the pathname given, outermost
+// first and each with a trailing slash
+func parentDirs(pathname string) (parents []string) {
+	for parent := path.Dir(strings.TrimSuffix(pathname, "/")); parent != "."; parent = path.Dir(parent) {
+		parents = append([]string{parent + "/"}, parents...)
+	}
+	return
+}
+
+// memberPathname converts the name of a tar or zip member to a valid HRX
+// pathname, returning an empty pathname for the top-level directory
+func memberPathname(name string, dir bool) (pathname string, err error) {
+	if pathname = strings.TrimSuffix(strings.TrimPrefix(name, "./"), "/"); pathname == "" || pathname == "." {
+		return "", nil
+	} else if err = checkPathname(pathname); err != nil {
+		return "", newError(name, 0, err, ErrBadFileEntry)
+	}
+	if dir {
+		pathname += "/"
+	}
+	return
+}
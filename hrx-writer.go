@@ -0,0 +1,256 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var _ io.WriteCloser = (*Writer)(nil)
+
+// Writer provides sequential writing of an HRX archive to any io.Writer
+// without holding the entries in memory. Writer is modeled after the
+// archive/tar.Writer, WriteHeader begins a new entry and Write appends to the
+// body contents of the current entry
+//
+// Writer produces the same output as Archive.String would for the same
+// entries. Like the Reader, the only state retained between entries is the
+// set of pathnames already written
+//
+// Unlike Archive.Set, Writer cannot raise the boundary after entries have been
+// written and instead returns an ErrBoundaryCollision error for any body or
+// comment lines which would be mistaken for boundary lines
+type Writer struct {
+	filename string
+	boundary int
+	comment  *string
+
+	w       io.Writer
+	line    int
+	header  *Header
+	partial []byte
+	head    string
+	checked bool
+	pending bool
+	started bool
+	closed  bool
+
+	paths *pathTracker
+	err   error
+}
+
+// NewWriter constructs a new Writer instance using the DefaultBoundary,
+// associating the filename with any errors returned
+func NewWriter(filename string, writer io.Writer) *Writer {
+	return &Writer{
+		filename: filename,
+		boundary: DefaultBoundary,
+		w:        writer,
+		paths:    newPathTracker(),
+	}
+}
+
+// SetBoundary changes the boundary size used by this Writer. SetBoundary
+// must be called before the first call to WriteHeader
+func (w *Writer) SetBoundary(size int) (err error) {
+	if size <= 0 {
+		return ErrBadBoundary
+	} else if w.started {
+		return ErrWriterStarted
+	}
+	w.boundary = size
+	return
+}
+
+// SetComment specifies the archive-level comment, written during Close
+func (w *Writer) SetComment(comment string) (err error) {
+	if !utf8.ValidString(comment) {
+		return newError(w.filename, w.line+1, ErrInvalidUnicode, ErrMalformedInput)
+	}
+	w.comment = &comment
+	return
+}
+
+// WriteHeader completes the current entry and begins a new one, including
+// any Header.Comment. The Header.Line is updated with the line number of the
+// boundary line written
+func (w *Writer) WriteHeader(hdr *Header) (err error) {
+	if err = w.check(); err != nil {
+		return
+	} else if err = w.flushPartial(); err != nil {
+		return
+	}
+
+	if hdr.Pathname == "" {
+		return newError(w.filename, w.line+1, nil, ErrBadFileEntry)
+	} else if ee := checkPathname(hdr.Pathname); ee != nil {
+		return newError(w.filename, w.line+1, ee, ErrBadFileEntry)
+	} else if !utf8.ValidString(hdr.Comment) {
+		return newError(w.filename, w.line+1, ErrInvalidUnicode, ErrMalformedInput)
+	} else if hasBoundaryLine(hdr.Comment, w.boundary) {
+		return newError(w.filename, w.line+1, nil, ErrBoundaryCollision)
+	}
+
+	var data string
+	if w.pending {
+		// separate the previous body from this boundary
+		data += "\n"
+	}
+	if hdr.Comment != "" {
+		data += newBoundary(w.boundary, "")
+		if data += hdr.Comment; !strings.HasSuffix(data, "\n") {
+			data += "\n"
+		}
+	}
+	line := w.line + strings.Count(data, "\n") + 1
+
+	if ee := w.paths.add(hdr.Pathname); ee != nil {
+		return newError(w.filename, line, nil, ee)
+	}
+
+	data += newBoundary(w.boundary, hdr.Pathname)
+	if err = w.write(data); err != nil {
+		return
+	}
+
+	hdr.Line = line
+	w.header = hdr
+	w.head, w.checked = "", false
+	w.pending = false
+	w.started = true
+	return
+}
+
+// Write appends to the body contents of the current entry. Writing to a
+// directory entry returns an ErrDirectoryHasContents error
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err = w.check(); err != nil {
+		return
+	} else if w.header == nil {
+		err = ErrWriteBeforeHeader
+		return
+	} else if len(p) == 0 {
+		return
+	} else if w.header.IsDir() {
+		err = newError(w.filename, w.header.Line, nil, ErrDirectoryHasContents)
+		return
+	}
+
+	// retain any incomplete utf-8 sequence for the next call to Write
+	data := append(w.partial, p...)
+	valid := len(data)
+	for idx := len(data) - 1; idx >= 0 && idx >= len(data)-utf8.UTFMax; idx-- {
+		if utf8.RuneStart(data[idx]) {
+			if !utf8.FullRune(data[idx:]) {
+				valid = idx
+			}
+			break
+		}
+	}
+
+	if !utf8.Valid(data[:valid]) {
+		err = newError(w.filename, w.line+1, ErrInvalidUnicode, ErrMalformedInput)
+		return
+	}
+	body := string(data[:valid])
+	if err = w.checkBoundary(body); err != nil {
+		return
+	}
+	w.partial = append([]byte{}, data[valid:]...)
+
+	if err = w.write(body); err == nil {
+		n = len(p)
+		w.pending = true
+	}
+	return
+}
+
+// Close writes any archive-level comment and prevents any further writing.
+// Close does not close the underlying io.Writer
+func (w *Writer) Close() (err error) {
+	if err = w.check(); err != nil {
+		return
+	} else if err = w.flushPartial(); err != nil {
+		return
+	}
+	if w.comment != nil && hasBoundaryLine(*w.comment, w.boundary) {
+		return newError(w.filename, w.line+1, nil, ErrBoundaryCollision)
+	}
+	w.closed = true
+	if w.comment != nil {
+		data := newBoundary(w.boundary, "") + *w.comment
+		if w.pending {
+			data = "\n" + data
+		}
+		err = w.write(data)
+	}
+	return
+}
+
+func (w *Writer) check() (err error) {
+	if w.err != nil {
+		return w.err
+	} else if w.closed {
+		return ErrWriteAfterClose
+	}
+	return
+}
+
+// checkBoundary tracks the start of each body line written, across calls to
+// Write, and returns an ErrBoundaryCollision error when a line begins with the
+// boundary of this Writer
+func (w *Writer) checkBoundary(body string) (err error) {
+	prefix := "<" + strings.Repeat("=", w.boundary) + ">"
+	head, checked, line := w.head, w.checked, w.line+1
+	for _, chunk := range strings.SplitAfter(body, "\n") {
+		if !checked {
+			if need := len(prefix) - len(head); len(chunk) > need {
+				head += chunk[:need]
+			} else {
+				head += chunk
+			}
+			if head == prefix {
+				return newError(w.filename, line, nil, ErrBoundaryCollision)
+			}
+			checked = len(head) >= len(prefix) || !strings.HasPrefix(prefix, head)
+		}
+		if strings.HasSuffix(chunk, "\n") {
+			head, checked = "", false
+			line += 1
+		}
+	}
+	w.head, w.checked = head, checked
+	return
+}
+
+func (w *Writer) flushPartial() (err error) {
+	if len(w.partial) > 0 {
+		w.partial = nil
+		err = newError(w.filename, w.line+1, ErrInvalidUnicode, ErrMalformedInput)
+	}
+	return
+}
+
+func (w *Writer) write(data string) (err error) {
+	if _, err = io.WriteString(w.w, data); err != nil {
+		// io errors are not recoverable
+		w.err = err
+		return
+	}
+	w.line += strings.Count(data, "\n")
+	return
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hrx
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriter(t *testing.T) {
+	Convey("Streaming Writer", t, func() {
+
+		Convey("matches Archive.String", func() {
+			a := New("testing.hrx", "")
+			So(a.Set("file", "contents", "comment"), ShouldBeNil)
+			So(a.Set("empty", "", ""), ShouldBeNil)
+			So(a.Set("dir/", "", ""), ShouldBeNil)
+			So(a.Set("dir/file", "subdir file\n", ""), ShouldBeNil)
+			a.SetComment("archive comment")
+
+			var buf strings.Builder
+			w := NewWriter("testing.hrx", &buf)
+			So(w.WriteHeader(&Header{Pathname: "file", Comment: "comment"}), ShouldBeNil)
+			_, err := io.WriteString(w, "cont")
+			So(err, ShouldBeNil)
+			_, err = io.WriteString(w, "ents")
+			So(err, ShouldBeNil)
+			So(w.WriteHeader(&Header{Pathname: "empty"}), ShouldBeNil)
+			hdr := &Header{Pathname: "dir/"}
+			So(w.WriteHeader(hdr), ShouldBeNil)
+			So(hdr.Line, ShouldEqual, 6)
+			_, err = io.WriteString(w, "nope")
+			So(errors.Is(err, ErrDirectoryHasContents), ShouldBeTrue)
+			So(w.WriteHeader(&Header{Pathname: "dir/file"}), ShouldBeNil)
+			_, err = io.WriteString(w, "subdir file\n")
+			So(err, ShouldBeNil)
+			So(w.SetComment("archive comment"), ShouldBeNil)
+			So(w.Close(), ShouldBeNil)
+			So(buf.String(), ShouldEqual, a.String())
+
+			So(w.WriteHeader(&Header{Pathname: "late"}), ShouldEqual, ErrWriteAfterClose)
+			So(w.Close(), ShouldEqual, ErrWriteAfterClose)
+		})
+
+		Convey("HRX Specification round-trip", func() {
+			for _, hrxname := range gTestDataFiles {
+				contents := TD.F(hrxname)
+				expected, err := ParseData(hrxname, contents)
+				if err != nil {
+					continue
+				}
+				var buf strings.Builder
+				r := NewReader(hrxname, strings.NewReader(contents))
+				w := NewWriter(hrxname, &buf)
+				for first := true; ; first = false {
+					hdr, ee := r.Next()
+					if first {
+						So(w.SetBoundary(r.Boundary()), ShouldBeNil)
+					}
+					if ee == io.EOF {
+						break
+					}
+					So(ee, ShouldBeNil)
+					So(w.WriteHeader(hdr), ShouldBeNil)
+					_, ee = io.Copy(w, r)
+					So(ee, ShouldBeNil)
+				}
+				if comment, ok := r.Comment(); ok {
+					So(w.SetComment(comment), ShouldBeNil)
+				}
+				So(w.Close(), ShouldBeNil)
+				a, ee := ParseData(hrxname, buf.String())
+				So(ee, ShouldBeNil)
+				So(a.List(), ShouldEqual, expected.List())
+				for _, entry := range expected.Entries() {
+					if actual := a.Entry(entry.GetPathname()); entry.IsFile() {
+						So(actual.GetBody(), ShouldEqual, entry.GetBody())
+						So(actual.GetComment(), ShouldEqual, entry.GetComment())
+					}
+				}
+			}
+		})
+
+		Convey("validation", func() {
+			var buf strings.Builder
+			w := NewWriter("testing.hrx", &buf)
+			_, err := w.Write([]byte("too soon"))
+			So(err, ShouldEqual, ErrWriteBeforeHeader)
+			So(w.SetBoundary(0), ShouldEqual, ErrBadBoundary)
+			So(w.SetBoundary(3), ShouldBeNil)
+			So(w.WriteHeader(&Header{Pathname: "dir/file"}), ShouldBeNil)
+			So(w.SetBoundary(4), ShouldEqual, ErrWriterStarted)
+			err = w.WriteHeader(&Header{Pathname: "dir/file"})
+			So(errors.Is(err, ErrDuplicatePath), ShouldBeTrue)
+			err = w.WriteHeader(&Header{Pathname: "dir"})
+			So(errors.Is(err, ErrFileAsParentDir), ShouldBeTrue)
+			err = w.WriteHeader(&Header{Pathname: "dir/file/sub"})
+			So(errors.Is(err, ErrFileAsParentDir), ShouldBeTrue)
+			err = w.WriteHeader(&Header{Pathname: "../file"})
+			So(errors.Is(err, ErrContainsRelPath), ShouldBeTrue)
+			err = w.WriteHeader(&Header{Pathname: "C:file"})
+			So(errors.Is(err, ErrContainsColon), ShouldBeTrue)
+			err = w.WriteHeader(&Header{Pathname: ""})
+			So(errors.Is(err, ErrBadFileEntry), ShouldBeTrue)
+
+			So(w.WriteHeader(&Header{Pathname: "snowman"}), ShouldBeNil)
+			snowman := []byte("☃")
+			_, err = w.Write(snowman[:1])
+			So(err, ShouldBeNil)
+			_, err = w.Write(snowman[1:])
+			So(err, ShouldBeNil)
+			_, err = w.Write([]byte{0xff, 'a'})
+			So(errors.Is(err, ErrInvalidUnicode), ShouldBeTrue)
+			So(w.Close(), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "<===> dir/file\n<===> snowman\n☃")
+		})
+
+	})
+}
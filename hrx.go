@@ -62,3 +62,33 @@ func ParseFile(path string) (hrx Archive, err error) {
 	hrx, err = parseFile(path)
 	return
 }
+
+// ParseOptions configures the ParseDataWith, ParseReaderWith and ParseFileWith
+// functions
+type ParseOptions struct {
+	// Lenient continues parsing after any problems are found, returning an
+	// Archive of the valid entries along with an error joining every *Error
+	// found, in line order (see errors.Join). Entries with invalid headers,
+	// duplicate pathnames or files used as parent directories are dropped,
+	// directories with contents are kept without the contents and any lines
+	// preceding the first header are ignored
+	Lenient bool
+}
+
+// ParseDataWith is like ParseData, with the options given
+func ParseDataWith[V string | []byte | []rune](filename string, data V, options *ParseOptions) (hrx Archive, err error) {
+	hrx, err = parseDataWith(filename, data, options)
+	return
+}
+
+// ParseReaderWith is like ParseReader, with the options given
+func ParseReaderWith(filename string, reader io.Reader, options *ParseOptions) (hrx Archive, err error) {
+	hrx, err = parseReaderWith(filename, reader, options)
+	return
+}
+
+// ParseFileWith is like ParseFile, with the options given
+func ParseFileWith(path string, options *ParseOptions) (hrx Archive, err error) {
+	hrx, err = parseFileWith(path, options)
+	return
+}
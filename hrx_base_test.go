@@ -201,16 +201,45 @@ directory comment
 					// SetBoundary encountering an error with item.parseHRX()
 					ee2 = a.Set("parser-error.hrx", "<=!=> not/a/thing\n", "")
 					So(ee2, ShouldBeNil)
+					before := a.String()
 					ee2 = a.SetBoundary(4)
 					So(ee2, ShouldNotBeNil)
+					// nothing is changed when the boundary cannot be set
+					So(a.String(), ShouldEqual, before)
 				})
 
 				Convey("recurse invalid nested archive", func() {
 					// SetBoundary encountering an error with ia.SetBoundary()
+					// the body collides with the boundary, Set fails to raise it
+					before := a.String()
 					ee2 = a.Set("recurse-parser-error.hrx", "<==> good.hrx\n<====> bad.hrx\n<==!==> not/a/thing\n", "")
+					So(ee2, ShouldNotBeNil)
+					So(a.Entry("recurse-parser-error.hrx"), ShouldBeNil)
+					So(a.String(), ShouldEqual, before)
+					ee2 = a.Set("recurse-parser-error.hrx", "<=====> good.hrx\n<======> bad.hrx\n<=!=> not/a/thing\n", "")
 					So(ee2, ShouldBeNil)
+					before = a.String()
 					ee2 = a.SetBoundary(5)
 					So(ee2, ShouldNotBeNil)
+					So(a.String(), ShouldEqual, before)
+				})
+
+				Convey("raising the boundary fails", func() {
+					b, ee := ParseData("raise.hrx", "<===> broken.hrx\nnot an archive\n<===> f\nbody\n")
+					So(ee, ShouldBeNil)
+					before := b.String()
+					// the nested archive fails to parse, the boundary is unchanged
+					So(b.SetComment("<===> collides"), ShouldNotBeNil)
+					_, ok := b.GetComment()
+					So(ok, ShouldBeFalse)
+					So(b.Set("f", "<===> collides", "comment"), ShouldNotBeNil)
+					body, comment, _ := b.Get("f")
+					So(body, ShouldEqual, "body\n")
+					So(comment, ShouldEqual, "")
+					So(b.Set("new.txt", "<===> collides", ""), ShouldNotBeNil)
+					So(b.List(), ShouldEqual, []string{"broken.hrx", "f"})
+					So(b.String(), ShouldEqual, before)
+					So(b.GetBoundary(), ShouldEqual, 3)
 				})
 
 			})
@@ -0,0 +1,133 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-corelibs/hrx"
+)
+
+// checker is the state of checking one archive level
+type checker struct {
+	archive  hrx.Archive
+	config   *Config
+	file     string
+	boundary int
+
+	// nodes is the concrete syntax of the archive
+	nodes []hrx.SyntaxNode
+	// headers are the header nodes of each entry
+	headers map[string]hrx.SyntaxNode
+	// entries are the file and directory entries
+	entries []hrx.Entry
+	// lines are the lines of the archive, without the trailing newlines
+	lines []string
+	// starts are the byte offsets of the start of each line
+	starts []int
+
+	rule     *Rule
+	severity Severity
+	problems []*Problem
+}
+
+func newChecker(a hrx.Archive, config *Config) (c *checker) {
+	c = &checker{
+		archive:  a,
+		config:   config,
+		file:     a.FileName(),
+		boundary: a.GetBoundary(),
+		nodes:    a.Syntax(),
+		headers:  make(map[string]hrx.SyntaxNode),
+		entries:  a.Entries(),
+	}
+	var offset int
+	for _, node := range c.nodes {
+		if node.Kind == hrx.SyntaxHeader {
+			c.headers[node.Pathname] = node
+		}
+		for _, line := range strings.SplitAfter(node.Text, "\n") {
+			if line == "" {
+				continue
+			} else if size := len(c.starts); size > 0 && !strings.HasSuffix(c.lines[size-1], "\n") {
+				// continuation of a line split across nodes
+				c.lines[size-1] += line
+			} else {
+				c.starts = append(c.starts, offset)
+				c.lines = append(c.lines, line)
+			}
+			offset += len(line)
+		}
+	}
+	for idx, line := range c.lines {
+		c.lines[idx] = strings.TrimSuffix(line, "\n")
+	}
+	return
+}
+
+// check runs all the enabled rules, or only the rule given when not nil, and
+// returns the problems found
+func (c *checker) check(only *Rule) (problems []*Problem) {
+	for _, rule := range gRules {
+		if only != nil && rule != only {
+			continue
+		} else if c.severity = c.config.Severity(rule.ID); c.severity == SeverityOff {
+			continue
+		}
+		c.rule = rule
+		rule.check(c)
+	}
+	problems, c.problems = c.problems, nil
+	return
+}
+
+// report records a problem found by the current rule, at the line and column
+// given
+func (c *checker) report(pathname string, line, column int, format string, argv ...interface{}) {
+	p := &Problem{
+		Rule:     c.rule.ID,
+		Severity: c.severity,
+		File:     c.file,
+		Pathname: pathname,
+		Line:     line,
+		Column:   column,
+		Message:  fmt.Sprintf(format, argv...),
+	}
+	if idx := line - 1; idx >= 0 && idx < len(c.lines) {
+		p.Source = c.lines[idx]
+		p.Offset = c.starts[idx]
+		if column > 1 {
+			runes := []rune(p.Source)
+			p.Offset += len(string(runes[:min(column-1, len(runes))]))
+		}
+	}
+	c.problems = append(c.problems, p)
+}
+
+// bodies returns the body nodes of all entries which are not nested archives
+func (c *checker) bodies() (nodes []hrx.SyntaxNode) {
+	for _, node := range c.nodes {
+		if node.Kind == hrx.SyntaxBody && !isHRX(node.Pathname) {
+			nodes = append(nodes, node)
+		}
+	}
+	return
+}
+
+// pathColumn returns the column of the pathname within each header line
+func (c *checker) pathColumn() (column int) {
+	return c.boundary + 4
+}
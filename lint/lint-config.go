@@ -0,0 +1,115 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+
+	"github.com/go-corelibs/hrx"
+)
+
+const (
+	// DefaultMaxEntries is the number of entries an archive may have before
+	// the archive-size rule reports it
+	DefaultMaxEntries = 500
+	// DefaultMaxLines is the number of lines an archive may have before the
+	// archive-size rule reports it
+	DefaultMaxLines = 5000
+)
+
+// Config is the configuration for Lint and Fix, the zero value uses the
+// default Severity of each Rule
+type Config struct {
+	// Rules overrides the default Severity of the rule IDs given, use
+	// SeverityOff to disable a rule
+	Rules map[string]Severity
+	// Order is the CompareFn used by the unsorted rule, nil uses
+	// hrx.LexicalOrder
+	Order hrx.CompareFn
+	// MaxEntries is the limit used by the archive-size rule, zero uses
+	// DefaultMaxEntries
+	MaxEntries int
+	// MaxLines is the limit used by the archive-size rule, zero uses
+	// DefaultMaxLines
+	MaxLines int
+}
+
+// Enable sets the rule IDs given to their default Severity, or to
+// SeverityWarning for rules which are disabled by default
+func (c *Config) Enable(ids ...string) (err error) {
+	for _, id := range ids {
+		if rule, ok := Lookup(id); !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownRule, id)
+		} else if rule.Severity != SeverityOff {
+			c.set(id, rule.Severity)
+		} else {
+			c.set(id, SeverityWarning)
+		}
+	}
+	return
+}
+
+// Disable sets the rule IDs given to SeverityOff
+func (c *Config) Disable(ids ...string) (err error) {
+	for _, id := range ids {
+		if _, ok := Lookup(id); !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownRule, id)
+		}
+		c.set(id, SeverityOff)
+	}
+	return
+}
+
+func (c *Config) set(id string, severity Severity) {
+	if c.Rules == nil {
+		c.Rules = make(map[string]Severity)
+	}
+	c.Rules[id] = severity
+}
+
+// Severity returns the configured Severity of the rule ID given, a nil
+// Config returns the default Severity of the rule
+func (c *Config) Severity(id string) (severity Severity) {
+	if c != nil {
+		if severity, ok := c.Rules[id]; ok {
+			return severity
+		}
+	}
+	if rule, ok := Lookup(id); ok {
+		return rule.Severity
+	}
+	return SeverityOff
+}
+
+func (c *Config) order() (fn hrx.CompareFn) {
+	if c != nil && c.Order != nil {
+		return c.Order
+	}
+	return hrx.LexicalOrder
+}
+
+func (c *Config) maxEntries() (size int) {
+	if c != nil && c.MaxEntries > 0 {
+		return c.MaxEntries
+	}
+	return DefaultMaxEntries
+}
+
+func (c *Config) maxLines() (size int) {
+	if c != nil && c.MaxLines > 0 {
+		return c.MaxLines
+	}
+	return DefaultMaxLines
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-corelibs/hrx"
+)
+
+// The following constants are the IDs of each Rule
+const (
+	// RuleTrailingWhitespace reports body lines ending with spaces or tabs
+	RuleTrailingWhitespace = "trailing-whitespace"
+	// RuleFinalNewline reports file bodies which do not end with a newline
+	RuleFinalNewline = "final-newline"
+	// RuleBoundaryLikeLine reports body and comment lines which look like
+	// entry headers with a boundary other than the archive boundary
+	RuleBoundaryLikeLine = "boundary-like-line"
+	// RuleDirectoryComment reports comments attached to directories
+	RuleDirectoryComment = "directory-comment"
+	// RuleImpliedDirectory reports parent directories of entries which are
+	// not declared with their own directory entries
+	RuleImpliedDirectory = "implied-directory"
+	// RuleUnsorted reports entries which are out of order, see Config.Order
+	RuleUnsorted = "unsorted"
+	// RuleArchiveSize reports archives with too many entries or lines, see
+	// Config.MaxEntries and Config.MaxLines
+	RuleArchiveSize = "archive-size"
+	// RuleInconsistentBoundary reports nested archives with a boundary
+	// other than one greater than the enclosing archive, as used by
+	// hrx.Archive.SetBoundary
+	RuleInconsistentBoundary = "inconsistent-boundary"
+)
+
+// Rule is a single style check
+type Rule struct {
+	// ID identifies this rule within a Config and each Problem reported
+	ID string
+	// Summary describes what this rule checks for
+	Summary string
+	// Severity is the default severity of this rule, rules which are
+	// SeverityOff by default must be enabled with Config.Enable
+	Severity Severity
+	// Fixable reports if Fix can correct the problems found by this rule
+	Fixable bool
+
+	check func(c *checker)
+	fix   func(a hrx.Archive, config *Config, problems []*Problem) (err error)
+}
+
+// gRules are all the rules, in the order Fix applies them
+var gRules = []*Rule{
+	{
+		ID:       RuleTrailingWhitespace,
+		Summary:  "body lines should not end with spaces or tabs",
+		Severity: SeverityWarning,
+		Fixable:  true,
+		check:    checkTrailingWhitespace,
+		fix:      fixTrailingWhitespace,
+	},
+	{
+		ID:       RuleFinalNewline,
+		Summary:  "file bodies should end with a newline",
+		Severity: SeverityInfo,
+		Fixable:  true,
+		check:    checkFinalNewline,
+		fix:      fixFinalNewline,
+	},
+	{
+		ID:       RuleBoundaryLikeLine,
+		Summary:  "lines which look like headers with another boundary are likely mistakes",
+		Severity: SeverityWarning,
+		check:    checkBoundaryLikeLine,
+	},
+	{
+		ID:       RuleDirectoryComment,
+		Summary:  "comments are not extracted with directories",
+		Severity: SeverityWarning,
+		check:    checkDirectoryComment,
+	},
+	{
+		ID:       RuleImpliedDirectory,
+		Summary:  "parent directories should be declared before their contents",
+		Severity: SeverityOff,
+		Fixable:  true,
+		check:    checkImpliedDirectory,
+		fix:      fixImpliedDirectory,
+	},
+	{
+		ID:       RuleUnsorted,
+		Summary:  "entries should be sorted",
+		Severity: SeverityOff,
+		Fixable:  true,
+		check:    checkUnsorted,
+		fix:      fixUnsorted,
+	},
+	{
+		ID:       RuleArchiveSize,
+		Summary:  "large archives should be split into smaller ones",
+		Severity: SeverityWarning,
+		check:    checkArchiveSize,
+	},
+	{
+		ID:       RuleInconsistentBoundary,
+		Summary:  "nested archives should use a boundary one greater than the enclosing archive",
+		Severity: SeverityWarning,
+		Fixable:  true,
+		check:    checkInconsistentBoundary,
+		fix:      fixInconsistentBoundary,
+	},
+}
+
+// Rules returns a copy of all the rules
+func Rules() (rules []Rule) {
+	for _, rule := range gRules {
+		rules = append(rules, *rule)
+	}
+	return
+}
+
+// Lookup returns a copy of the rule with the ID given
+func Lookup(id string) (rule Rule, ok bool) {
+	for _, r := range gRules {
+		if ok = r.ID == id; ok {
+			rule = *r
+			return
+		}
+	}
+	return
+}
+
+func checkTrailingWhitespace(c *checker) {
+	for _, node := range c.bodies() {
+		for idx, line := range strings.Split(node.Text, "\n") {
+			if trimmed := strings.TrimRight(line, " \t"); len(trimmed) < len(line) {
+				c.report(node.Pathname, node.Line+idx, utf8.RuneCountInString(trimmed)+1, "trailing whitespace")
+			}
+		}
+	}
+}
+
+func fixTrailingWhitespace(a hrx.Archive, _ *Config, problems []*Problem) (err error) {
+	for _, pathname := range pathnames(problems) {
+		body, comment, _ := a.Get(pathname)
+		lines := strings.Split(body, "\n")
+		for idx, line := range lines {
+			lines[idx] = strings.TrimRight(line, " \t")
+		}
+		if err = a.Set(pathname, strings.Join(lines, "\n"), comment); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func checkFinalNewline(c *checker) {
+	for _, node := range c.bodies() {
+		if !strings.HasSuffix(node.Text, "\n") {
+			last := node.Text[strings.LastIndex(node.Text, "\n")+1:]
+			c.report(node.Pathname, node.Line+strings.Count(node.Text, "\n"), utf8.RuneCountInString(last)+1, "missing final newline")
+		}
+	}
+}
+
+func fixFinalNewline(a hrx.Archive, _ *Config, problems []*Problem) (err error) {
+	for _, pathname := range pathnames(problems) {
+		body, comment, _ := a.Get(pathname)
+		if err = a.Set(pathname, body+"\n", comment); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func checkBoundaryLikeLine(c *checker) {
+	for _, node := range c.nodes {
+		if node.Kind != hrx.SyntaxComment && (node.Kind != hrx.SyntaxBody || isHRX(node.Pathname)) {
+			continue
+		}
+		for s := hrx.NewScanner(strings.NewReader(node.Text)); s.Scan(); {
+			if _, line, boundary, _, header, err := s.Get(); header && boundary > 0 && err == nil {
+				c.report(node.Pathname, node.Line+line-1, 1, "line looks like a header with a boundary of %d, the archive boundary is %d", boundary, c.boundary)
+			}
+		}
+	}
+}
+
+func checkDirectoryComment(c *checker) {
+	for _, node := range c.nodes {
+		if node.Kind == hrx.SyntaxBoundary && strings.HasSuffix(node.Pathname, "/") {
+			c.report(node.Pathname, node.Line, 0, "directory %q has a comment", node.Pathname)
+		}
+	}
+}
+
+// implied is a directory which is not declared, and the first entry within it
+type implied struct {
+	dir    string
+	before string
+}
+
+// impliedDirs returns the parent directories of the entries given which are
+// not declared, in the order they are first implied
+func impliedDirs(entries []hrx.Entry) (list []implied) {
+	declared := make(map[string]bool)
+	for _, item := range entries {
+		if item.IsDir() {
+			declared[item.GetPathname()] = true
+		}
+	}
+	for _, item := range entries {
+		pathname := item.GetPathname()
+		for idx := 0; idx < len(pathname)-1; idx++ {
+			if dir := pathname[:idx+1]; pathname[idx] == '/' && !declared[dir] {
+				declared[dir] = true
+				list = append(list, implied{dir: dir, before: pathname})
+			}
+		}
+	}
+	return
+}
+
+func checkImpliedDirectory(c *checker) {
+	for _, found := range impliedDirs(c.entries) {
+		c.report(found.before, c.headers[found.before].Line, c.pathColumn(), "directory %q is implied but not declared", found.dir)
+	}
+}
+
+func fixImpliedDirectory(a hrx.Archive, _ *Config, _ []*Problem) (err error) {
+	for _, found := range impliedDirs(a.Entries()) {
+		if err = a.InsertBefore(found.before, found.dir, "", ""); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func checkUnsorted(c *checker) {
+	order := c.config.order()
+	for idx := 1; idx < len(c.entries); idx++ {
+		prev, item := c.entries[idx-1], c.entries[idx]
+		if order(prev, item) > 0 {
+			pathname := item.GetPathname()
+			c.report(pathname, c.headers[pathname].Line, c.pathColumn(), "%q sorts before %q", pathname, prev.GetPathname())
+		}
+	}
+}
+
+func fixUnsorted(a hrx.Archive, config *Config, _ []*Problem) (err error) {
+	a.Sort(config.order())
+	return
+}
+
+func checkArchiveSize(c *checker) {
+	if limit := c.config.maxEntries(); len(c.entries) > limit {
+		c.report("", c.headers[c.entries[limit].GetPathname()].Line, 0, "archive has %d entries, more than %d", len(c.entries), limit)
+	}
+	if limit := c.config.maxLines(); len(c.lines) > limit {
+		c.report("", limit+1, 0, "archive has %d lines, more than %d", len(c.lines), limit)
+	}
+}
+
+func checkInconsistentBoundary(c *checker) {
+	for _, node := range c.nodes {
+		if node.Kind != hrx.SyntaxBody || !isHRX(node.Pathname) {
+			continue
+		}
+		if ia, err := c.archive.ParseHRX(node.Pathname); err == nil {
+			if size := ia.GetBoundary(); size != c.boundary+1 {
+				c.report(node.Pathname, node.Line, 1, "nested archive boundary is %d, expected %d", size, c.boundary+1)
+			}
+		}
+	}
+}
+
+func fixInconsistentBoundary(a hrx.Archive, _ *Config, _ []*Problem) (err error) {
+	// SetBoundary rewrites all nested archive boundaries, even when the size
+	// is unchanged
+	err = a.SetBoundary(a.GetBoundary())
+	return
+}
+
+// pathnames returns the unique pathnames of the problems given, in order
+func pathnames(problems []*Problem) (list []string) {
+	seen := make(map[string]bool)
+	for _, p := range problems {
+		if !seen[p.Pathname] {
+			seen[p.Pathname] = true
+			list = append(list, p.Pathname)
+		}
+	}
+	return
+}
+
+func isHRX(pathname string) bool {
+	return strings.HasSuffix(pathname, ".hrx")
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint provides style checks for human-readable archives
+//
+// The hrx package rejects archives which do not follow the specification,
+// the checks here are for valid archives which are merely untidy. Each Rule
+// has an ID, used to configure the Severity of the Problems it reports, and
+// the Fixable rules can be corrected automatically with Fix
+//
+// Nested archives are checked along with the archive containing them, the
+// Problems found within are positioned within the enclosing archive and
+// their Pathname is a nested address (see hrx.NestedPath)
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/go-corelibs/hrx"
+)
+
+var (
+	ErrUnknownRule = errors.New("unknown lint rule")
+)
+
+// Severity is the importance of a Problem
+type Severity string
+
+const (
+	// SeverityOff disables a rule
+	SeverityOff Severity = "off"
+	// SeverityInfo is for matters of taste
+	SeverityInfo Severity = "info"
+	// SeverityWarning is for problems which are likely to be mistakes
+	SeverityWarning Severity = "warning"
+	// SeverityError is for problems which must be corrected
+	SeverityError Severity = "error"
+)
+
+// Problem is a single lint finding, positioned like an *hrx.Error
+type Problem struct {
+	// Rule is the ID of the Rule which found this problem
+	Rule string
+	// Severity is the configured severity of the Rule
+	Severity Severity
+	// File is the filename of the archive checked
+	File string
+	// Pathname is the entry this problem was found within, empty for
+	// problems with the archive as a whole
+	Pathname string
+	// Line is the line number of the archive where this problem is
+	Line int
+	// Column is the column number, counted in runes starting from one, of
+	// the line where this problem is, zero when the entire line is meant
+	Column int
+	// Offset is the byte offset within the archive of the Column, or of the
+	// start of the Line when the Column is zero
+	Offset int
+	// Source is the line of the archive where this problem is, without the
+	// trailing newline
+	Source string
+	// Message describes this problem
+	Message string
+}
+
+// Error returns the problem in the conventional file:line:column: message
+// form, followed by the Severity and Rule
+func (p *Problem) Error() string {
+	if p.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", p.File, p.Line, p.Column, p.Severity, p.Message, p.Rule)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s (%s)", p.File, p.Line, p.Severity, p.Message, p.Rule)
+}
+
+// Format implements fmt.Formatter. The %+v verb renders the Error message
+// followed by the Source line with a caret marking the Column and the Rule
+// summary, all other verbs render the Error message
+func (p *Problem) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		_, _ = io.WriteString(f, p.Error()+p.snippet())
+	case verb == 'q':
+		_, _ = fmt.Fprintf(f, "%q", p.Error())
+	default:
+		_, _ = io.WriteString(f, p.Error())
+	}
+}
+
+// snippet renders the Source line, caret and Rule summary of this problem
+func (p *Problem) snippet() (text string) {
+	gutter := fmt.Sprintf("%d", p.Line)
+	blank := strings.Repeat(" ", len(gutter))
+	text += fmt.Sprintf("\n%s | %s", gutter, p.Source)
+	if p.Column > 0 {
+		text += fmt.Sprintf("\n%s | %s^", blank, strings.Repeat(" ", p.Column-1))
+	}
+	if rule, ok := Lookup(p.Rule); ok {
+		text += fmt.Sprintf("\n%s = %s: %s", blank, rule.ID, rule.Summary)
+	}
+	return
+}
+
+// Lint checks the archive given, and all nested archives within, with the
+// rules enabled by the config. A nil config uses the default Severity of
+// each Rule. The problems are returned in the order they are found within
+// the archive
+func Lint(a hrx.Archive, config *Config) (problems []*Problem) {
+	problems = lint(a, config, nil)
+	slices.SortStableFunc(problems, func(x, y *Problem) int {
+		if x.Line != y.Line {
+			return x.Line - y.Line
+		}
+		return x.Column - y.Column
+	})
+	return
+}
+
+// lint checks the archive and all nested archives, with only the rule given
+// when not nil
+func lint(a hrx.Archive, config *Config, only *Rule) (problems []*Problem) {
+	c := newChecker(a, config)
+	problems = c.check(only)
+
+	for _, node := range c.nodes {
+		if node.Kind != hrx.SyntaxBody || !strings.HasSuffix(node.Pathname, ".hrx") {
+			continue
+		}
+		ia, err := a.ParseHRX(node.Pathname)
+		if err != nil {
+			// invalid nested archives are reported by hrx.ParseData
+			continue
+		}
+		for _, p := range lint(ia, config, only) {
+			p.File = c.file
+			if p.Pathname != "" {
+				p.Pathname = hrx.NestedPath(node.Pathname, p.Pathname)
+			} else {
+				p.Pathname = node.Pathname
+			}
+			p.Line += node.Line - 1
+			p.Offset += node.Offset
+			problems = append(problems, p)
+		}
+	}
+	return
+}
+
+// Fix corrects the problems found by the Fixable rules enabled by the config
+// and returns the problems fixed, as they were found before each fix was
+// applied. Nested archives are fixed before the archive containing them,
+// each being written back to its entry with Set
+func Fix(a hrx.Archive, config *Config) (fixed []*Problem, err error) {
+	for _, item := range a.Entries() {
+		if !item.IsHRX() {
+			continue
+		}
+		ia, ee := item.ParseHRX()
+		if ee != nil {
+			continue
+		}
+		var nested []*Problem
+		if nested, err = Fix(ia, config); err != nil {
+			return
+		} else if len(nested) == 0 {
+			continue
+		}
+		pathname := item.GetPathname()
+		for _, p := range nested {
+			p.File = a.FileName()
+			if p.Pathname != "" {
+				p.Pathname = hrx.NestedPath(pathname, p.Pathname)
+			} else {
+				p.Pathname = pathname
+			}
+		}
+		fixed = append(fixed, nested...)
+		if err = a.Set(pathname, ia.String(), item.GetComment()); err != nil {
+			return
+		}
+	}
+
+	for _, rule := range gRules {
+		if rule.fix == nil || config.Severity(rule.ID) == SeverityOff {
+			continue
+		}
+		c := newChecker(a, config)
+		if problems := c.check(rule); len(problems) > 0 {
+			if err = rule.fix(a, config, problems); err != nil {
+				return
+			}
+			fixed = append(fixed, problems...)
+		}
+	}
+	return
+}
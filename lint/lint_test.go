@@ -0,0 +1,137 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/hrx"
+)
+
+const tLintHRX = "<===> b.txt\n" +
+	"trailing \n" +
+	`<===>
+about the directory
+<===> dir/
+<===> a.txt
+no newline
+<===> dir/sub/file.txt
+<=> looks like a header
+
+<===> nested.hrx
+<==> inner.txt
+` + "inner\t\n" + `
+<===> z.txt
+last
+`
+
+// tFound returns the rule and line of each problem given
+func tFound(problems []*Problem) (found []string) {
+	for _, p := range problems {
+		found = append(found, fmt.Sprintf("%s:%d:%d %s", p.Pathname, p.Line, p.Column, p.Rule))
+	}
+	return
+}
+
+func TestLint(t *testing.T) {
+	Convey("Linting archives", t, func() {
+		a, err := hrx.ParseData("lint.hrx", tLintHRX)
+		So(err, ShouldBeNil)
+
+		Convey("default rules", func() {
+			problems := Lint(a, nil)
+			So(tFound(problems), ShouldEqual, []string{
+				"b.txt:2:9 trailing-whitespace",
+				"b.txt:2:10 final-newline",
+				"dir/:3:0 directory-comment",
+				"a.txt:7:11 final-newline",
+				"dir/sub/file.txt:9:1 boundary-like-line",
+				"nested.hrx:12:1 inconsistent-boundary",
+				"nested.hrx//inner.txt:13:6 trailing-whitespace",
+			})
+
+			p := problems[0]
+			So(p.Severity, ShouldEqual, SeverityWarning)
+			So(p.Source, ShouldEqual, "trailing ")
+			So(p.Offset, ShouldEqual, len("<===> b.txt\ntrailing"))
+			So(p.Error(), ShouldEqual, "lint.hrx:2:9: warning: trailing whitespace (trailing-whitespace)")
+			So(fmt.Sprintf("%+v", p), ShouldEqual, "lint.hrx:2:9: warning: trailing whitespace (trailing-whitespace)\n"+
+				"2 | trailing \n"+
+				"  |         ^\n"+
+				"  = trailing-whitespace: body lines should not end with spaces or tabs")
+			nested := problems[len(problems)-1]
+			So(nested.File, ShouldEqual, "lint.hrx")
+			So(nested.Source, ShouldEqual, "inner\t")
+			So(nested.Offset, ShouldEqual, len(tLintHRX[:len(tLintHRX)-len("\t\n\n<===> z.txt\nlast\n")]))
+		})
+
+		Convey("configured rules", func() {
+			config := &Config{MaxEntries: 4, MaxLines: 10}
+			So(config.Enable(RuleUnsorted, RuleImpliedDirectory), ShouldBeNil)
+			So(config.Disable(RuleFinalNewline, RuleTrailingWhitespace, RuleBoundaryLikeLine), ShouldBeNil)
+			So(config.Enable("nope"), ShouldWrap, ErrUnknownRule)
+			So(config.Severity(RuleUnsorted), ShouldEqual, SeverityWarning)
+			So(config.Severity(RuleFinalNewline), ShouldEqual, SeverityOff)
+			So(tFound(Lint(a, config)), ShouldEqual, []string{
+				"dir/:3:0 directory-comment",
+				"a.txt:6:7 unsorted",
+				"dir/sub/file.txt:8:7 implied-directory",
+				":11:0 archive-size",
+				":11:0 archive-size",
+				"nested.hrx:12:1 inconsistent-boundary",
+			})
+		})
+
+		Convey("rules", func() {
+			rules := Rules()
+			So(rules, ShouldHaveLength, 8)
+			rule, ok := Lookup(RuleInconsistentBoundary)
+			So(ok, ShouldBeTrue)
+			So(rule.Fixable, ShouldBeTrue)
+			_, ok = Lookup("nope")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Fix", func() {
+			config := &Config{}
+			So(config.Enable(RuleUnsorted, RuleImpliedDirectory), ShouldBeNil)
+			fixed, err := Fix(a, config)
+			So(err, ShouldBeNil)
+			So(tFound(fixed), ShouldHaveLength, 7)
+			So(tFound(Lint(a, config)), ShouldEqual, []string{
+				"dir/:7:0 directory-comment",
+				"dir/sub/file.txt:12:1 boundary-like-line",
+			})
+			_, err = hrx.ParseData("fixed.hrx", a.String())
+			So(err, ShouldBeNil)
+			So(a.List(), ShouldEqual, []string{"a.txt", "b.txt", "dir/", "dir/sub/", "dir/sub/file.txt", "nested.hrx", "z.txt"})
+			body, _, _ := a.Get("b.txt")
+			So(body, ShouldEqual, "trailing\n")
+			body, _, _ = a.Get(hrx.NestedPath("nested.hrx", "inner.txt"))
+			So(body, ShouldEqual, "inner\n")
+			ia, err := a.ParseHRX("nested.hrx")
+			So(err, ShouldBeNil)
+			So(ia.GetBoundary(), ShouldEqual, 4)
+
+			fixed, err = Fix(a, config)
+			So(err, ShouldBeNil)
+			So(fixed, ShouldBeEmpty)
+		})
+
+	})
+}
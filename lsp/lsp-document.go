@@ -0,0 +1,330 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-corelibs/hrx"
+)
+
+// document is an open text document
+//
+// The boundary lines of the archive are indexed as spans using a Scanner and
+// each incremental change only rescans the lines from the span containing the
+// start of the change until the first unchanged boundary line following it
+type document struct {
+	uri     string
+	version int
+	text    string
+	// starts are the byte offsets of the start of each line
+	starts []int
+	// boundary is the archive boundary, zero when the first line is not a
+	// header
+	boundary int
+	// spans are the boundary lines of the archive, in order
+	spans []span
+}
+
+// span is a boundary line of an archive
+type span struct {
+	// line is the zero-based line number
+	line int
+	// pathname is the entry pathname, empty for comments
+	pathname string
+}
+
+// node is an entry, or comment, of a document and is derived from the spans
+// of an archive level when needed
+type node struct {
+	// address is the nested address of the entry, empty for comments
+	address string
+	// pathname is the entry pathname, empty for comments
+	pathname string
+	// line is the zero-based line number of the boundary line
+	line int
+	// boundary is the boundary of the archive level
+	boundary int
+	// start and stop are the byte offsets of the body, the newline preceding
+	// the next boundary line is not included
+	start, stop int
+	// children are the entries of a nested archive
+	children []*node
+}
+
+func newDocument(uri string, version int, text string) (d *document) {
+	d = &document{uri: uri, version: version}
+	d.setText(text)
+	d.index()
+	return
+}
+
+// setText replaces the text and line offsets, without updating the spans
+func (d *document) setText(text string) {
+	d.text = text
+	d.starts = []int{0}
+	for idx := 0; idx < len(text); idx++ {
+		if text[idx] == '\n' {
+			d.starts = append(d.starts, idx+1)
+		}
+	}
+}
+
+// index scans the entire document for spans
+func (d *document) index() {
+	d.spans, d.boundary, _ = scan(d.text, 0, 0, nil)
+}
+
+// apply updates the document with the change given, a change without a
+// range replaces the entire document
+func (d *document) apply(change TextDocumentContentChangeEvent) {
+	if change.Range == nil {
+		d.setText(change.Text)
+		d.index()
+		return
+	}
+	start, end := d.offset(change.Range.Start), d.offset(change.Range.End)
+	if end < start {
+		start, end = end, start
+	}
+	first, last := d.lineOf(start), d.lineOf(end)
+	lines := len(d.starts)
+	d.setText(d.text[:start] + change.Text + d.text[end:])
+	d.reindex(first, last, len(d.starts)-lines)
+}
+
+// reindex updates the spans after the lines from first to last, inclusive,
+// were replaced and the number of lines changed by delta
+func (d *document) reindex(first, last, delta int) {
+	resume := -1
+	for idx, s := range d.spans {
+		if s.line > first {
+			break
+		}
+		resume = idx
+	}
+	if first == 0 || resume < 0 || d.boundary == 0 {
+		// the archive boundary may have changed
+		d.index()
+		return
+	}
+
+	// old spans following the change, by line number
+	following := make(map[int]int)
+	for idx, s := range d.spans {
+		if s.line > last {
+			following[s.line] = idx
+		}
+	}
+
+	from := d.spans[resume].line
+	var tail int
+	spans, _, resumed := scan(d.text[d.starts[from]:], from, d.boundary, func(s span) bool {
+		if s.line > last+delta {
+			if idx, ok := following[s.line-delta]; ok && d.spans[idx].pathname == s.pathname {
+				tail = idx
+				return true
+			}
+		}
+		return false
+	})
+
+	updated := append(d.spans[:resume:resume], spans...)
+	if resumed {
+		for _, s := range d.spans[tail:] {
+			updated = append(updated, span{line: s.line + delta, pathname: s.pathname})
+		}
+	}
+	d.spans = updated
+}
+
+// scan returns the spans of the archive text given using a Scanner, numbering
+// the lines from the first line given. When the boundary is zero, it is taken
+// from the first line which must be a header. Scanning stops when the resume
+// func returns true for a span, which is not included
+func scan(text string, first, boundary int, resume func(s span) bool) (spans []span, size int, resumed bool) {
+	size = boundary
+	for s := hrx.NewScanner(strings.NewReader(text)); s.Scan(); {
+		content, line, found, pathname, header, _ := s.Get()
+		if content == "" {
+			// end of input
+			break
+		} else if size == 0 {
+			if !header || found == 0 {
+				// not an archive
+				return
+			}
+			size = found
+		}
+		if header && found == size {
+			next := span{line: first + line - 1, pathname: pathname}
+			if resume != nil && resume(next) {
+				resumed = true
+				return
+			}
+			spans = append(spans, next)
+		}
+	}
+	return
+}
+
+// nodes returns the entries and comments of the top-level archive
+func (d *document) nodes() (list []*node) {
+	return d.level(d.spans, d.boundary, len(d.text), "")
+}
+
+// level returns the nodes of an archive level from its spans, where stop is
+// the byte offset of the end of the level and prefix is the nested address
+// of the level
+func (d *document) level(spans []span, boundary, stop int, prefix string) (list []*node) {
+	for idx, s := range spans {
+		n := &node{pathname: s.pathname, line: s.line, boundary: boundary}
+		n.start = min(d.lineStart(s.line+1), stop)
+		if n.stop = stop; idx+1 < len(spans) {
+			n.stop = d.lineStart(spans[idx+1].line) - 1
+		}
+		n.stop = max(n.stop, n.start)
+		if s.pathname != "" {
+			n.address = prefix + s.pathname
+		}
+		if strings.HasSuffix(s.pathname, ".hrx") && n.stop > n.start {
+			if inner, size, _ := scan(d.text[n.start:n.stop], s.line+1, 0, nil); len(inner) > 0 {
+				n.children = d.level(inner, size, n.stop, n.address+hrx.NestedSeparator)
+			}
+		}
+		list = append(list, n)
+	}
+	return
+}
+
+// find returns the innermost node containing the line given
+func (d *document) find(list []*node, line int) (found *node) {
+	for _, n := range list {
+		if n.line <= line && line <= d.lastLine(n) {
+			if child := d.find(n.children, line); child != nil {
+				return child
+			}
+			return n
+		}
+	}
+	return
+}
+
+// resolve returns the node of the nested address given
+func (d *document) resolve(address string) (found *node) {
+	list := d.nodes()
+	for _, pathname := range hrx.SplitNestedPath(address) {
+		found = nil
+		for _, n := range list {
+			if n.pathname != "" && n.pathname == pathname {
+				found = n
+				break
+			}
+		}
+		if found == nil {
+			return
+		}
+		list = found.children
+	}
+	return
+}
+
+// body returns the body text of the node
+func (d *document) body(n *node) (text string) {
+	return d.text[n.start:n.stop]
+}
+
+// lastLine returns the line number of the last line of the node
+func (d *document) lastLine(n *node) (line int) {
+	if n.stop > n.start {
+		return d.lineOf(n.stop - 1)
+	}
+	return n.line
+}
+
+// pathRange returns the range of the pathname within the header of the node
+func (d *document) pathRange(n *node) (r Range) {
+	start := n.boundary + 3
+	r.Start = Position{Line: n.line, Character: start}
+	r.End = Position{Line: n.line, Character: start + utf16Len(n.pathname)}
+	return
+}
+
+// lineRange returns the range of the lines given, inclusive
+func (d *document) lineRange(first, last int) (r Range) {
+	r.Start = Position{Line: first}
+	r.End = Position{Line: last, Character: utf16Len(d.lineText(last))}
+	return
+}
+
+// lineStart returns the byte offset of the start of the line given
+func (d *document) lineStart(line int) (offset int) {
+	if line < len(d.starts) {
+		return d.starts[line]
+	}
+	return len(d.text)
+}
+
+// lineOf returns the line number of the byte offset given
+func (d *document) lineOf(offset int) (line int) {
+	return sort.Search(len(d.starts), func(idx int) bool {
+		return d.starts[idx] > offset
+	}) - 1
+}
+
+// lineText returns the line given, without the trailing newline
+func (d *document) lineText(line int) (text string) {
+	if line < 0 || line >= len(d.starts) {
+		return
+	}
+	text = d.text[d.starts[line]:d.lineStart(line+1)]
+	return strings.TrimSuffix(text, "\n")
+}
+
+// offset returns the byte offset of the position given, positions beyond
+// the end of a line are clamped to the end of the line
+func (d *document) offset(pos Position) (offset int) {
+	if pos.Line >= len(d.starts) {
+		return len(d.text)
+	} else if pos.Line < 0 {
+		return 0
+	}
+	text := d.lineText(pos.Line)
+	var units int
+	for idx, r := range text {
+		if units >= pos.Character {
+			return d.starts[pos.Line] + idx
+		}
+		units += utf16RuneLen(r)
+	}
+	return d.starts[pos.Line] + len(text)
+}
+
+// utf16Len returns the number of UTF-16 code units of the text given, which
+// is how LSP positions count characters
+func utf16Len(text string) (size int) {
+	for _, r := range text {
+		size += utf16RuneLen(r)
+	}
+	return
+}
+
+func utf16RuneLen(r rune) (size int) {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
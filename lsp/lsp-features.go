@@ -0,0 +1,158 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-corelibs/hrx"
+)
+
+// diagnostic converts an *hrx.Error into a Diagnostic, errors with a column
+// cover the character at the column and all others cover the entire line
+func (d *document) diagnostic(e *hrx.Error) (diagnostic Diagnostic) {
+	line := max(e.Line-1, 0)
+	diagnostic.Range = d.lineRange(line, line)
+	diagnostic.Severity = severityError
+	diagnostic.Source = "hrx"
+	diagnostic.Message = e.Base.Error()
+	if e.Wrap != nil {
+		diagnostic.Message += ": " + e.Wrap.Error()
+	}
+	if e.Column > 0 {
+		runes := []rune(d.lineText(line))
+		idx := min(e.Column-1, len(runes))
+		diagnostic.Range.Start.Character = utf16Len(string(runes[:idx]))
+		diagnostic.Range.End.Character = diagnostic.Range.Start.Character
+		if idx < len(runes) {
+			diagnostic.Range.End.Character += utf16RuneLen(runes[idx])
+		}
+	}
+	return
+}
+
+// symbols returns the DocumentSymbols of the entries given
+func (d *document) symbols(list []*node) (symbols []DocumentSymbol) {
+	symbols = []DocumentSymbol{}
+	for _, n := range list {
+		if n.pathname == "" {
+			continue
+		}
+		symbol := DocumentSymbol{
+			Name:           n.pathname,
+			Detail:         d.describe(n),
+			Kind:           symbolFile,
+			Range:          d.lineRange(n.line, d.lastLine(n)),
+			SelectionRange: d.pathRange(n),
+		}
+		if strings.HasSuffix(n.pathname, "/") {
+			symbol.Kind = symbolNamespace
+		} else if strings.HasSuffix(n.pathname, ".hrx") {
+			symbol.Kind = symbolModule
+			if len(n.children) > 0 {
+				symbol.Children = d.symbols(n.children)
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+	return
+}
+
+// folds returns the FoldingRanges of the entries and comments given, and of
+// the entries of any nested archives
+func (d *document) folds(list []*node) (folds []FoldingRange) {
+	folds = []FoldingRange{}
+	for _, n := range list {
+		if last := d.lastLine(n); last > n.line {
+			fold := FoldingRange{StartLine: n.line, EndLine: last}
+			if n.pathname == "" {
+				fold.Kind = foldingComment
+			}
+			folds = append(folds, fold)
+		}
+		folds = append(folds, d.folds(n.children)...)
+	}
+	return
+}
+
+// describe returns a summary of the entry given
+func (d *document) describe(n *node) (summary string) {
+	body := d.body(n)
+	switch {
+	case strings.HasSuffix(n.pathname, "/"):
+		return "directory"
+	case strings.HasSuffix(n.pathname, ".hrx"):
+		var entries int
+		for _, child := range n.children {
+			if child.pathname != "" {
+				entries += 1
+			}
+		}
+		return fmt.Sprintf("nested archive, %d entries", entries)
+	}
+	lines := strings.Count(body, "\n")
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		lines += 1
+	}
+	return fmt.Sprintf("%d bytes, %d lines", len(body), lines)
+}
+
+// header returns the entry with a header on the line given
+func (d *document) header(line int) (found *node) {
+	if n := d.find(d.nodes(), line); n != nil && n.line == line && n.pathname != "" {
+		found = n
+	}
+	return
+}
+
+// word returns the text surrounding the position given, delimited by spaces,
+// quotes and brackets and without any trailing punctuation
+func (d *document) word(pos Position) (word string) {
+	if pos.Line < 0 || pos.Line >= len(d.starts) {
+		return
+	}
+	text := d.lineText(pos.Line)
+	at := d.offset(pos) - d.starts[pos.Line]
+	delimiter := func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("\"'`()[]{}<>,;", r)
+	}
+	start, end := 0, len(text)
+	if idx := strings.LastIndexFunc(text[:at], delimiter); idx >= 0 {
+		_, size := utf8.DecodeRuneInString(text[idx:])
+		start = idx + size
+	}
+	if idx := strings.IndexFunc(text[at:], delimiter); idx >= 0 {
+		end = at + idx
+	}
+	return strings.TrimRight(text[start:end], ".:")
+}
+
+// renameEdit returns the WorkspaceEdit renaming the entry given, the rename
+// is checked with hrx.Archive.Rename before any edit is made
+func (d *document) renameEdit(n *node, newName string) (edit *WorkspaceEdit, err error) {
+	prefix := strings.TrimSuffix(n.address, n.pathname)
+	// lenient parsing always returns an archive
+	a, _ := hrx.ParseDataWith(d.uri, d.text, &hrx.ParseOptions{Lenient: true})
+	if err = a.Rename(n.address, prefix+newName); err != nil {
+		return
+	}
+	edit = &WorkspaceEdit{Changes: map[string][]TextEdit{
+		d.uri: {{Range: d.pathRange(n), NewText: newName}},
+	}}
+	return
+}
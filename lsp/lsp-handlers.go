@@ -0,0 +1,303 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-corelibs/hrx"
+)
+
+// handlerFn is the signature of each method handler, notifications have
+// their result discarded
+type handlerFn func(s *Server, params json.RawMessage) (result interface{}, err error)
+
+var gHandlers = map[string]handlerFn{
+	"initialize":                  (*Server).initialize,
+	"initialized":                 (*Server).initialized,
+	"shutdown":                    (*Server).shutdown,
+	"textDocument/didOpen":        (*Server).didOpen,
+	"textDocument/didChange":      (*Server).didChange,
+	"textDocument/didClose":       (*Server).didClose,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/foldingRange":   (*Server).foldingRange,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/prepareRename":  (*Server).prepareRename,
+	"textDocument/rename":         (*Server).rename,
+	"textDocument/codeAction":     (*Server).codeAction,
+	"workspace/executeCommand":    (*Server).executeCommand,
+}
+
+// decode unmarshals the params given into the value given
+func decode(params json.RawMessage, value interface{}) (err error) {
+	if ee := json.Unmarshal(params, value); ee != nil {
+		err = newResponseError(CodeInvalidParams, "%v", ee)
+	}
+	return
+}
+
+// document returns the open document with the URI given
+func (s *Server) document(uri string) (d *document, err error) {
+	if d = s.docs[uri]; d == nil {
+		err = newResponseError(CodeInvalidParams, "document not open: %s", uri)
+	}
+	return
+}
+
+func (s *Server) initialize(_ json.RawMessage) (result interface{}, err error) {
+	s.ready = true
+	result = map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			// incremental changes
+			"textDocumentSync":       map[string]interface{}{"openClose": true, "change": 2},
+			"documentSymbolProvider": true,
+			"foldingRangeProvider":   true,
+			"hoverProvider":          true,
+			"definitionProvider":     true,
+			"renameProvider":         map[string]interface{}{"prepareProvider": true},
+			"codeActionProvider":     map[string]interface{}{"codeActionKinds": []string{kindRefactorRewrite}},
+			"executeCommandProvider": map[string]interface{}{"commands": []string{CommandRenameEntry}},
+		},
+		"serverInfo": map[string]interface{}{"name": "hrx"},
+	}
+	return
+}
+
+func (s *Server) initialized(_ json.RawMessage) (result interface{}, err error) {
+	return
+}
+
+func (s *Server) shutdown(_ json.RawMessage) (result interface{}, err error) {
+	s.closing = true
+	return
+}
+
+func (s *Server) didOpen(params json.RawMessage) (result interface{}, err error) {
+	var p DidOpenTextDocumentParams
+	if err = decode(params, &p); err != nil {
+		return
+	}
+	d := newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+	s.docs[d.uri] = d
+	err = s.publish(d)
+	return
+}
+
+func (s *Server) didChange(params json.RawMessage) (result interface{}, err error) {
+	var p DidChangeTextDocumentParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	d.version = p.TextDocument.Version
+	for _, change := range p.ContentChanges {
+		d.apply(change)
+	}
+	err = s.publish(d)
+	return
+}
+
+func (s *Server) didClose(params json.RawMessage) (result interface{}, err error) {
+	var p TextDocumentParams
+	if err = decode(params, &p); err != nil {
+		return
+	}
+	delete(s.docs, p.TextDocument.URI)
+	err = s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	return
+}
+
+// publish sends the diagnostics found by lenient parsing of the document
+func (s *Server) publish(d *document) (err error) {
+	diagnostics := []Diagnostic{}
+	if _, ee := hrx.ParseDataWith(d.uri, d.text, &hrx.ParseOptions{Lenient: true}); ee != nil {
+		errs := []error{ee}
+		if joined, ok := ee.(interface{ Unwrap() []error }); ok {
+			errs = joined.Unwrap()
+		}
+		for _, eee := range errs {
+			if e, ok := hrx.AsError(eee); ok {
+				diagnostics = append(diagnostics, d.diagnostic(e))
+			}
+		}
+	}
+	err = s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: d.uri, Version: d.version, Diagnostics: diagnostics})
+	return
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (result interface{}, err error) {
+	var p TextDocumentParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	result = d.symbols(d.nodes())
+	return
+}
+
+func (s *Server) foldingRange(params json.RawMessage) (result interface{}, err error) {
+	var p TextDocumentParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	result = d.folds(d.nodes())
+	return
+}
+
+func (s *Server) hover(params json.RawMessage) (result interface{}, err error) {
+	var p TextDocumentPositionParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	if n := d.find(d.nodes(), p.Position.Line); n != nil && n.pathname != "" {
+		r := d.pathRange(n)
+		result = &Hover{
+			Contents: MarkupContent{Kind: "markdown", Value: fmt.Sprintf("`%s`\n\n%s", n.address, d.describe(n))},
+			Range:    &r,
+		}
+	}
+	return
+}
+
+func (s *Server) definition(params json.RawMessage) (result interface{}, err error) {
+	var p TextDocumentPositionParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	word := d.word(p.Position)
+	if word == "" {
+		return
+	}
+	// references within a nested archive are relative to that archive
+	var found *node
+	if n := d.find(d.nodes(), p.Position.Line); n != nil && n.address != "" {
+		if idx := strings.LastIndex(n.address, hrx.NestedSeparator); idx >= 0 {
+			found = d.resolve(n.address[:idx+len(hrx.NestedSeparator)] + word)
+		}
+	}
+	if found == nil {
+		found = d.resolve(word)
+	}
+	if found != nil {
+		result = &Location{URI: d.uri, Range: d.pathRange(found)}
+	}
+	return
+}
+
+func (s *Server) prepareRename(params json.RawMessage) (result interface{}, err error) {
+	var p TextDocumentPositionParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	if n := d.header(p.Position.Line); n != nil {
+		result = d.pathRange(n)
+	}
+	return
+}
+
+func (s *Server) rename(params json.RawMessage) (result interface{}, err error) {
+	var p RenameParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	n := d.header(p.Position.Line)
+	if n == nil {
+		err = newResponseError(CodeRequestFailed, "not an entry header")
+		return
+	}
+	result, err = d.renameEdit(n, p.NewName)
+	return
+}
+
+func (s *Server) codeAction(params json.RawMessage) (result interface{}, err error) {
+	var p CodeActionParams
+	var d *document
+	if err = decode(params, &p); err != nil {
+		return
+	} else if d, err = s.document(p.TextDocument.URI); err != nil {
+		return
+	}
+	actions := []CodeAction{}
+	if n := d.header(p.Range.Start.Line); n != nil {
+		actions = append(actions, CodeAction{
+			Title: "Rename entry",
+			Kind:  kindRefactorRewrite,
+			Command: &Command{
+				Title:     "Rename entry",
+				Command:   CommandRenameEntry,
+				Arguments: []interface{}{d.uri, n.address},
+			},
+		})
+	}
+	result = actions
+	return
+}
+
+func (s *Server) executeCommand(params json.RawMessage) (result interface{}, err error) {
+	var p ExecuteCommandParams
+	if err = decode(params, &p); err != nil {
+		return
+	} else if p.Command != CommandRenameEntry {
+		err = newResponseError(CodeInvalidParams, "unknown command: %s", p.Command)
+		return
+	} else if len(p.Arguments) != 3 {
+		err = newResponseError(CodeInvalidParams, "%s expects the document URI, entry address and new pathname", CommandRenameEntry)
+		return
+	}
+
+	var uri, address, newName string
+	for idx, value := range []*string{&uri, &address, &newName} {
+		if err = decode(p.Arguments[idx], value); err != nil {
+			return
+		}
+	}
+
+	var d *document
+	if d, err = s.document(uri); err != nil {
+		return
+	}
+	n := d.resolve(address)
+	if n == nil {
+		err = fmt.Errorf("%s: %w", address, hrx.ErrNotFound)
+		return
+	}
+	var edit *WorkspaceEdit
+	if edit, err = d.renameEdit(n, newName); err == nil {
+		err = s.call("workspace/applyEdit", &ApplyWorkspaceEditParams{Label: "Rename entry", Edit: *edit})
+	}
+	return
+}
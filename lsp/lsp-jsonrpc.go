@@ -0,0 +1,120 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSON-RPC and LSP error codes
+const (
+	CodeParseError           = -32700
+	CodeInvalidRequest       = -32600
+	CodeMethodNotFound       = -32601
+	CodeInvalidParams        = -32602
+	CodeServerNotInitialized = -32002
+	CodeRequestFailed        = -32803
+)
+
+// ResponseError is the error of a failed request
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func newResponseError(code int, format string, argv ...interface{}) (err *ResponseError) {
+	return &ResponseError{Code: code, Message: fmt.Sprintf(format, argv...)}
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// message is an incoming request, notification or response
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isNotification reports if the message does not expect a response
+func (m *message) isNotification() bool {
+	return len(m.ID) == 0
+}
+
+// response is the successful response to a request, the result is always
+// present even when null
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+// errorResponse is the response to a failed request
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *ResponseError  `json:"error"`
+}
+
+// request is an outgoing request, or notification when the ID is zero
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// readMessage reads the next Content-Length framed message body
+func readMessage(reader *bufio.Reader) (body []byte, err error) {
+	length := -1
+	for {
+		var line string
+		if line, err = reader.ReadString('\n'); err != nil {
+			return
+		} else if line = strings.TrimRight(line, "\r\n"); line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				err = fmt.Errorf("%w: %v", ErrBadHeader, err)
+				return
+			}
+		}
+	}
+	if length < 0 {
+		err = ErrBadHeader
+		return
+	}
+	body = make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	return
+}
+
+// writeMessage writes the value given as a Content-Length framed message
+func writeMessage(writer io.Writer, value interface{}) (err error) {
+	var data []byte
+	if data, err = json.Marshal(value); err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lsp provides a Language Server Protocol server for human-readable
+// archives
+//
+// The Server publishes the diagnostics found by lenient parsing (see
+// hrx.ParseOptions) and provides document symbols, folding ranges and hover
+// information for each entry, go-to-definition for nested addresses (see
+// hrx.NestedPath) and renaming entries, with textDocument/rename or the
+// "hrx.renameEntry" code action
+//
+// Documents are indexed with a Scanner, incremental changes only rescan the
+// entries which were changed
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	ErrBadHeader  = errors.New("bad message header")
+	ErrNoShutdown = errors.New("exit without shutdown")
+)
+
+// CommandRenameEntry is the workspace/executeCommand command of the rename
+// entry code action. The arguments are the document URI, the nested address
+// of the entry and the new pathname
+const CommandRenameEntry = "hrx.renameEntry"
+
+// Server is a Language Server Protocol server, see Serve
+type Server struct {
+	writer io.Writer
+	docs   map[string]*document
+
+	// ready is set by the initialize request
+	ready bool
+	// closing is set by the shutdown request
+	closing bool
+	nextID  int
+
+	mutex *sync.Mutex
+}
+
+// NewServer constructs a new Server instance
+func NewServer() *Server {
+	return &Server{
+		docs:  make(map[string]*document),
+		mutex: &sync.Mutex{},
+	}
+}
+
+// Serve reads messages from the reader and writes responses, and any
+// notifications, to the writer until the exit notification is received or
+// the reader is closed. Serve returns ErrNoShutdown if the exit notification
+// was not preceded by a shutdown request
+func (s *Server) Serve(reader io.Reader, writer io.Writer) (err error) {
+	s.writer = writer
+	buffered := bufio.NewReader(reader)
+	for {
+		var body []byte
+		if body, err = readMessage(buffered); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return
+		}
+
+		var msg message
+		if ee := json.Unmarshal(body, &msg); ee != nil {
+			_ = s.respond(json.RawMessage("null"), nil, newResponseError(CodeParseError, "%v", ee))
+			continue
+		} else if msg.Method == "exit" {
+			if !s.closing {
+				err = ErrNoShutdown
+			}
+			return
+		} else if msg.Method == "" {
+			// responses to requests made by the server are not used
+			continue
+		}
+
+		result, ee := s.handle(&msg)
+		if !msg.isNotification() {
+			if err = s.respond(msg.ID, result, ee); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle dispatches the message to its handler
+func (s *Server) handle(msg *message) (result interface{}, err error) {
+	fn, ok := gHandlers[msg.Method]
+	switch {
+	case !ok:
+		err = newResponseError(CodeMethodNotFound, "method not found: %s", msg.Method)
+	case !s.ready && msg.Method != "initialize":
+		err = newResponseError(CodeServerNotInitialized, "server not initialized")
+	case s.closing:
+		err = newResponseError(CodeInvalidRequest, "server is shutting down")
+	default:
+		result, err = fn(s, msg.Params)
+	}
+	return
+}
+
+// respond writes the response to the request ID given
+func (s *Server) respond(id json.RawMessage, result interface{}, err error) error {
+	if err == nil {
+		return s.write(&response{JSONRPC: "2.0", ID: id, Result: result})
+	}
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		re = newResponseError(CodeRequestFailed, "%v", err)
+	}
+	return s.write(&errorResponse{JSONRPC: "2.0", ID: id, Error: re})
+}
+
+// notify writes a notification to the client
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(&request{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// call writes a request to the client, the response is not waited for
+func (s *Server) call(method string, params interface{}) error {
+	s.mutex.Lock()
+	s.nextID += 1
+	id := s.nextID
+	s.mutex.Unlock()
+	return s.write(&request{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+}
+
+func (s *Server) write(value interface{}) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return writeMessage(s.writer, value)
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tURI = "file:///testing.hrx"
+
+const tLspHRX = `<===> one.txt
+one
+<===>
+about two
+<===> two.txt
+two
+two
+<===> nested.hrx
+<====> inner.txt
+see one.txt and nested.hrx//inner.txt
+<====> deeper.hrx
+<=====> deep.txt
+deep
+<===> dir/
+`
+
+// tMessage is any message received from the server
+type tMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+// tClient is a client connected to a Server with in-process pipes
+type tClient struct {
+	writer   *io.PipeWriter
+	incoming chan *tMessage
+	pending  []*tMessage
+	done     chan error
+	nextID   int
+}
+
+func tStart() (c *tClient) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c = &tClient{
+		writer:   clientOut,
+		incoming: make(chan *tMessage, 100),
+		done:     make(chan error, 1),
+	}
+	go func() {
+		c.done <- NewServer().Serve(serverIn, serverOut)
+		_ = serverOut.Close()
+	}()
+	go func() {
+		reader := bufio.NewReader(clientIn)
+		for {
+			body, err := readMessage(reader)
+			if err != nil {
+				close(c.incoming)
+				return
+			}
+			var m tMessage
+			_ = json.Unmarshal(body, &m)
+			c.incoming <- &m
+		}
+	}()
+	return
+}
+
+// request sends a request and decodes the result of the response into the
+// value given, any server messages received first are kept for next
+func (c *tClient) request(method string, params, value interface{}) (err *ResponseError) {
+	c.nextID += 1
+	So(writeMessage(c.writer, &request{JSONRPC: "2.0", ID: c.nextID, Method: method, Params: params}), ShouldBeNil)
+	for m := range c.incoming {
+		if m.Method != "" {
+			c.pending = append(c.pending, m)
+		} else if string(m.ID) == strconv.Itoa(c.nextID) {
+			if m.Error != nil {
+				return m.Error
+			} else if value != nil {
+				So(json.Unmarshal(m.Result, value), ShouldBeNil)
+			}
+			return
+		}
+	}
+	return
+}
+
+func (c *tClient) notify(method string, params interface{}) {
+	So(writeMessage(c.writer, &request{JSONRPC: "2.0", Method: method, Params: params}), ShouldBeNil)
+}
+
+// next returns the next message from the server with the method given and
+// decodes its params into the value given
+func (c *tClient) next(method string, value interface{}) (m *tMessage) {
+	for {
+		if len(c.pending) > 0 {
+			m, c.pending = c.pending[0], c.pending[1:]
+		} else if m = <-c.incoming; m == nil {
+			return
+		}
+		if m.Method == method {
+			So(json.Unmarshal(m.Params, value), ShouldBeNil)
+			return
+		}
+	}
+}
+
+func tPosition(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func tRange(line, start, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+func TestServer(t *testing.T) {
+	Convey("Language server", t, func() {
+		c := tStart()
+
+		So(c.request("textDocument/hover", tPosition(tURI, 0, 0), nil).Code, ShouldEqual, CodeServerNotInitialized)
+		var initialized map[string]interface{}
+		So(c.request("initialize", map[string]interface{}{}, &initialized), ShouldBeNil)
+		So(initialized["capabilities"], ShouldNotBeNil)
+		c.notify("initialized", map[string]interface{}{})
+		So(c.request("nope", nil, nil).Code, ShouldEqual, CodeMethodNotFound)
+
+		c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{URI: tURI, LanguageID: "hrx", Version: 1, Text: tLspHRX},
+		})
+		var published PublishDiagnosticsParams
+		So(c.next("textDocument/publishDiagnostics", &published), ShouldNotBeNil)
+		So(published.URI, ShouldEqual, tURI)
+		So(published.Diagnostics, ShouldBeEmpty)
+
+		Convey("document symbols and folding ranges", func() {
+			var symbols []DocumentSymbol
+			So(c.request("textDocument/documentSymbol", &TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: tURI}}, &symbols), ShouldBeNil)
+			So(symbols, ShouldHaveLength, 4)
+			So(symbols[0].Name, ShouldEqual, "one.txt")
+			So(symbols[0].Detail, ShouldEqual, "3 bytes, 1 lines")
+			So(symbols[0].SelectionRange, ShouldEqual, tRange(0, 6, 13))
+			So(symbols[1].Range, ShouldEqual, Range{Start: Position{Line: 4}, End: Position{Line: 6, Character: 3}})
+			So(symbols[2].Kind, ShouldEqual, symbolModule)
+			So(symbols[2].Children, ShouldHaveLength, 2)
+			So(symbols[2].Children[1].Children[0].Name, ShouldEqual, "deep.txt")
+			So(symbols[2].Children[1].Children[0].SelectionRange, ShouldEqual, tRange(11, 8, 16))
+			So(symbols[3].Kind, ShouldEqual, symbolNamespace)
+
+			var folds []FoldingRange
+			So(c.request("textDocument/foldingRange", &TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: tURI}}, &folds), ShouldBeNil)
+			So(folds, ShouldEqual, []FoldingRange{
+				{StartLine: 0, EndLine: 1},
+				{StartLine: 2, EndLine: 3, Kind: foldingComment},
+				{StartLine: 4, EndLine: 6},
+				{StartLine: 7, EndLine: 12},
+				{StartLine: 8, EndLine: 9},
+				{StartLine: 10, EndLine: 12},
+				{StartLine: 11, EndLine: 12},
+			})
+		})
+
+		Convey("hover and definition", func() {
+			var hover Hover
+			So(c.request("textDocument/hover", tPosition(tURI, 12, 0), &hover), ShouldBeNil)
+			So(hover.Contents.Value, ShouldEqual, "`nested.hrx//deeper.hrx//deep.txt`\n\n4 bytes, 1 lines")
+			So(*hover.Range, ShouldEqual, tRange(11, 8, 16))
+			var none *Hover
+			So(c.request("textDocument/hover", tPosition(tURI, 3, 0), &none), ShouldBeNil)
+			So(none, ShouldBeNil)
+
+			var location Location
+			So(c.request("textDocument/definition", tPosition(tURI, 9, 5), &location), ShouldBeNil)
+			So(location, ShouldEqual, Location{URI: tURI, Range: tRange(0, 6, 13)})
+			So(c.request("textDocument/definition", tPosition(tURI, 9, 20), &location), ShouldBeNil)
+			So(location, ShouldEqual, Location{URI: tURI, Range: tRange(8, 7, 16)})
+			var missing *Location
+			So(c.request("textDocument/definition", tPosition(tURI, 9, 0), &missing), ShouldBeNil)
+			So(missing, ShouldBeNil)
+		})
+
+		Convey("rename entries", func() {
+			var r *Range
+			So(c.request("textDocument/prepareRename", tPosition(tURI, 4, 8), &r), ShouldBeNil)
+			So(*r, ShouldEqual, tRange(4, 6, 13))
+			r = nil
+			So(c.request("textDocument/prepareRename", tPosition(tURI, 5, 0), &r), ShouldBeNil)
+			So(r, ShouldBeNil)
+
+			var edit WorkspaceEdit
+			So(c.request("textDocument/rename", &RenameParams{TextDocument: TextDocumentIdentifier{URI: tURI}, Position: Position{Line: 4}, NewName: "2.txt"}, &edit), ShouldBeNil)
+			So(edit.Changes[tURI], ShouldEqual, []TextEdit{{Range: tRange(4, 6, 13), NewText: "2.txt"}})
+			failed := c.request("textDocument/rename", &RenameParams{TextDocument: TextDocumentIdentifier{URI: tURI}, Position: Position{Line: 4}, NewName: "one.txt"}, nil)
+			So(failed, ShouldNotBeNil)
+			So(failed.Code, ShouldEqual, CodeRequestFailed)
+			So(failed.Message, ShouldContainSubstring, "duplicate path")
+
+			var actions []CodeAction
+			So(c.request("textDocument/codeAction", &CodeActionParams{TextDocument: TextDocumentIdentifier{URI: tURI}, Range: tRange(8, 0, 0)}, &actions), ShouldBeNil)
+			So(actions, ShouldHaveLength, 1)
+			So(actions[0].Command.Command, ShouldEqual, CommandRenameEntry)
+			So(actions[0].Command.Arguments, ShouldEqual, []interface{}{tURI, "nested.hrx//inner.txt"})
+
+			arguments := append(actions[0].Command.Arguments, "renamed.txt")
+			So(c.request("workspace/executeCommand", map[string]interface{}{"command": CommandRenameEntry, "arguments": arguments}, nil), ShouldBeNil)
+			var applied ApplyWorkspaceEditParams
+			So(c.next("workspace/applyEdit", &applied), ShouldNotBeNil)
+			So(applied.Edit.Changes[tURI], ShouldEqual, []TextEdit{{Range: tRange(8, 7, 16), NewText: "renamed.txt"}})
+			So(c.request("workspace/executeCommand", map[string]interface{}{"command": CommandRenameEntry, "arguments": actions[0].Command.Arguments}, nil).Code, ShouldEqual, CodeInvalidParams)
+		})
+
+		Convey("incremental changes and diagnostics", func() {
+			c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+				TextDocument: VersionedTextDocumentIdentifier{URI: tURI, Version: 2},
+				ContentChanges: []TextDocumentContentChangeEvent{
+					{Range: &Range{Start: Position{Line: 5}, End: Position{Line: 5}}, Text: "<===> one.txt\n"},
+					{Range: &Range{Start: Position{Line: 1}, End: Position{Line: 1, Character: 3}}, Text: "🙂"},
+				},
+			})
+			So(c.next("textDocument/publishDiagnostics", &published), ShouldNotBeNil)
+			So(published.Version, ShouldEqual, 2)
+			So(published.Diagnostics, ShouldEqual, []Diagnostic{{
+				Range:    tRange(5, 6, 7),
+				Severity: severityError,
+				Source:   "hrx",
+				Message:  "duplicate path",
+			}})
+
+			var symbols []DocumentSymbol
+			So(c.request("textDocument/documentSymbol", &TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: tURI}}, &symbols), ShouldBeNil)
+			So(symbols, ShouldHaveLength, 5)
+			So(symbols[0].Detail, ShouldEqual, "4 bytes, 1 lines")
+			So(symbols[0].Range.End, ShouldEqual, Position{Line: 1, Character: 2})
+			So(symbols[3].SelectionRange, ShouldEqual, tRange(8, 6, 16))
+
+			c.notify("textDocument/didClose", &TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: tURI}})
+			So(c.next("textDocument/publishDiagnostics", &published), ShouldNotBeNil)
+			So(published.Diagnostics, ShouldBeEmpty)
+			So(c.request("textDocument/foldingRange", &TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: tURI}}, nil).Code, ShouldEqual, CodeInvalidParams)
+		})
+
+		So(c.request("shutdown", nil, nil), ShouldBeNil)
+		So(c.request("textDocument/hover", tPosition(tURI, 0, 0), nil).Code, ShouldEqual, CodeInvalidRequest)
+		c.notify("exit", nil)
+		So(<-c.done, ShouldBeNil)
+	})
+}
+
+func TestIncremental(t *testing.T) {
+	Convey("Incremental changes match a full rescan", t, func() {
+		snippets := []string{"<===> new.txt\n", "body\n", "<==> other\n", "<===>\n", "<====> nested\n", "", "x", "\n"}
+		random := rand.New(rand.NewSource(1))
+		d := newDocument(tURI, 1, tLspHRX)
+		for step := 0; step < 500; step++ {
+			start := Position{Line: random.Intn(len(d.starts) + 1), Character: random.Intn(8)}
+			end := Position{Line: start.Line + random.Intn(3), Character: random.Intn(8)}
+			if d.offset(end) < d.offset(start) {
+				end = start
+			}
+			d.apply(TextDocumentContentChangeEvent{Range: &Range{Start: start, End: end}, Text: snippets[random.Intn(len(snippets))]})
+			expected := newDocument(tURI, 1, d.text)
+			So(d.boundary, ShouldEqual, expected.boundary)
+			So(d.spans, ShouldEqual, expected.spans)
+			if step%50 == 0 && d.boundary == 0 {
+				// keep the document an archive
+				d.apply(TextDocumentContentChangeEvent{Text: tLspHRX + d.text})
+			}
+		}
+	})
+}